@@ -6,27 +6,63 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/codeready-toolchain/argocd-mcp/internal/argocd"
+	"github.com/codeready-toolchain/argocd-mcp/internal/config"
 	"github.com/codeready-toolchain/argocd-mcp/internal/server"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/dynamic"
 )
 
-var transport, listen, argocdURL, argocdToken string
-var argocdInsecure, debug bool
+var transport, listen, argocdURL, argocdToken, argocdTokenFile, argocdUsername, argocdPassword, configFile string
+var argocdCAFile, argocdClientCert, argocdClientKey, argocdTLSMinVersion, argocdServerName string
+var argocdBasePath, argocdProxy, argocdNamespace, argocdKubeconfig, argocdKubeContext string
+var argocdInsecure, watchApplications, debug bool
+var timeout, retryInitialBackoff, retryMaxBackoff, retryMaxTime, cacheTTL time.Duration
+var uncachedTools []string
+var maxRetries, argocdRateLimitBurst, argocdMaxInFlight int
+var argocdRateLimit float64
+
+// kubeFlags the flags of the connection to the Kubernetes API server, when the Applications are read through the Kubernetes API
+var kubeFlags = []string{"argocd-namespace", "argocd-kubeconfig", "argocd-kube-context"}
+
+// cfg the configuration loaded from the file (if any)
+var cfg config.Config
 
 func init() {
-	startServerCmd.Flags().StringVar(&argocdURL, "argocd-url", "", "Specify the URL of the Argo CD server to query (required)")
-	if err := startServerCmd.MarkFlagRequired("argocd-url"); err != nil {
-		panic(err)
-	}
-	startServerCmd.Flags().StringVar(&argocdToken, "argocd-token", "", "Specify the token to include in the Authorization header (required)")
-	if err := startServerCmd.MarkFlagRequired("argocd-token"); err != nil {
-		panic(err)
-	}
+	startServerCmd.Flags().StringVar(&configFile, "config", "", "Specify the path to the YAML (or JSON) configuration file")
+	startServerCmd.Flags().StringVar(&argocdURL, "argocd-url", "", "Specify the URL of the Argo CD server to query (required unless 'instances' are configured in the file)")
+	startServerCmd.Flags().StringVar(&argocdToken, "argocd-token", "", "Specify the token to include in the Authorization header")
+	startServerCmd.Flags().StringVar(&argocdTokenFile, "argocd-token-file", "", "Specify the path to the file containing the token to include in the Authorization header")
+	startServerCmd.Flags().StringVar(&argocdUsername, "argocd-username", "", "Specify the name of the Argo CD local user to log in as, instead of using a token")
+	startServerCmd.Flags().StringVar(&argocdPassword, "argocd-password", "", "Specify the password of the Argo CD local user to log in as")
 	startServerCmd.Flags().BoolVar(&argocdInsecure, "insecure", false, "Allow insecure TLS connections to the Argo CD server")
+	startServerCmd.Flags().StringVar(&argocdCAFile, "argocd-ca-file", "", "Specify the path to the PEM-encoded CA bundle to verify the certificate of the Argo CD server")
+	startServerCmd.Flags().StringVar(&argocdClientCert, "argocd-client-cert", "", "Specify the path to the PEM-encoded client certificate for mutual TLS with the Argo CD server")
+	startServerCmd.Flags().StringVar(&argocdClientKey, "argocd-client-key", "", "Specify the path to the PEM-encoded client key for mutual TLS with the Argo CD server")
+	startServerCmd.Flags().StringVar(&argocdTLSMinVersion, "argocd-tls-min-version", "1.2", "Specify the minimum TLS version of the connection to the Argo CD server: '1.0', '1.1', '1.2' or '1.3'")
+	startServerCmd.Flags().StringVar(&argocdServerName, "argocd-server-name", "", "Specify the server name to use for SNI and to verify the certificate of the Argo CD server (instead of the host of the URL)")
+	startServerCmd.Flags().StringVar(&argocdBasePath, "argocd-base-path", "", "Specify the path under which Argo CD is served, if not already in the URL (eg: '/argocd' when the Argo CD API server runs with '--rootpath=/argocd')")
+	startServerCmd.Flags().StringVar(&argocdProxy, "argocd-proxy", "", "Specify the URL of the HTTP proxy to connect to the Argo CD server (defaults to the HTTPS_PROXY and HTTP_PROXY env vars, and the hosts in the NO_PROXY env var are not proxied)")
+	startServerCmd.Flags().Float64Var(&argocdRateLimit, "argocd-rate-limit", 0, "Specify the maximum number of requests per second to the Argo CD server, on average (0 for no limit)")
+	startServerCmd.Flags().IntVar(&argocdRateLimitBurst, "argocd-rate-limit-burst", 0, "Specify the maximum number of requests sent at once to the Argo CD server when the rate limit is set (defaults to the rate limit rounded up)")
+	startServerCmd.Flags().IntVar(&argocdMaxInFlight, "argocd-max-in-flight", 0, "Specify the maximum number of requests in flight to the Argo CD server (0 for no limit)")
+	startServerCmd.Flags().StringVar(&argocdNamespace, "argocd-namespace", "", "Specify the namespace of the Argo CD control plane, to read the Applications through the Kubernetes API instead of the Argo CD API server (instead of '--argocd-url')")
+	startServerCmd.Flags().StringVar(&argocdKubeconfig, "argocd-kubeconfig", "", "Specify the path to the kubeconfig file to connect to the Kubernetes API server when '--argocd-namespace' is set (defaults to the KUBECONFIG env var, '~/.kube/config' or the in-cluster configuration)")
+	startServerCmd.Flags().StringVar(&argocdKubeContext, "argocd-kube-context", "", "Specify the kubeconfig context to use when '--argocd-namespace' is set (defaults to the current context)")
+	startServerCmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Specify the timeout of each attempt of a request to Argo CD (0 for no timeout)")
+	startServerCmd.Flags().IntVar(&maxRetries, "max-retries", 3, "Specify the maximum number of retries of the idempotent requests to Argo CD which failed with a transient error (0 to disable the retries)")
+	startServerCmd.Flags().DurationVar(&retryInitialBackoff, "retry-initial-backoff", 500*time.Millisecond, "Specify the delay before the first retry of a request to Argo CD, which is doubled after each retry")
+	startServerCmd.Flags().DurationVar(&retryMaxBackoff, "retry-max-backoff", 10*time.Second, "Specify the maximum delay between 2 attempts of a request to Argo CD")
+	startServerCmd.Flags().DurationVar(&retryMaxTime, "retry-max-time", time.Minute, "Specify the maximum time spent on the retries of the requests to Argo CD during a tool call, after which the failed requests are no longer retried (0 for no limit)")
+	startServerCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", 10*time.Second, "Specify how long the responses of Argo CD are cached (0 to disable the cache)")
+	startServerCmd.Flags().StringSliceVar(&uncachedTools, "uncached-tools", nil, "Specify the tools which never use the cache of the Argo CD responses (eg: 'applicationEvents,resourceDiff')")
+	startServerCmd.Flags().BoolVar(&watchApplications, "watch-applications", false, "Keep an in-memory copy of all the Applications, updated with the stream of Application events of Argo CD, to answer the requests without listing the Applications each time")
 	startServerCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug mode")
 	startServerCmd.Flags().StringVar(&transport, "transport", "http", "Choose between 'stdio' or 'http' transport")
 	startServerCmd.Flags().StringVar(&listen, "listen", ":8080", "Specify the host and port to listen on when using the 'http' transport")
@@ -45,10 +81,55 @@ func Execute() {
 var startServerCmd = &cobra.Command{
 	Use:   "argocd-mcp",
 	Short: "Start the Argo CD MCP server",
-	PreRunE: func(_ *cobra.Command, _ []string) error {
+	Long: "Start the Argo CD MCP server.\n\n" +
+		"Each flag can also be set with an environment variable prefixed with '" + config.EnvPrefix + "' (eg: '" + config.EnvVar("argocd-url") + "' for '--argocd-url'), " +
+		"or in the configuration file (eg: 'argocdURL'). The precedence order is: flag > env > file.",
+	PreRunE: func(cmd *cobra.Command, _ []string) error {
+		var err error
+		if cfg, err = config.Bind(cmd.Flags(), os.LookupEnv); err != nil {
+			return err
+		}
 		if transport != "stdio" && transport != "http" {
 			return fmt.Errorf("invalid transport: choose between 'http' and 'stdio'")
 		}
+		if timeout < 0 || maxRetries < 0 || retryMaxTime < 0 || retryInitialBackoff <= 0 || retryMaxBackoff < retryInitialBackoff {
+			return fmt.Errorf("invalid retry settings: the timeout, the max retries and the max retry time cannot be negative, and the max backoff cannot be lower than the initial backoff")
+		}
+		if cacheTTL < 0 {
+			return fmt.Errorf("invalid cache TTL: cannot be negative")
+		}
+		if len(cfg.Instances) > 0 {
+			// the flags set from the env vars are also marked as changed
+			singleInstanceFlags := []string{}
+			cmd.Flags().Visit(func(f *pflag.Flag) {
+				if strings.HasPrefix(f.Name, "argocd-") || f.Name == "insecure" {
+					singleInstanceFlags = append(singleInstanceFlags, "--"+f.Name)
+				}
+			})
+			if len(singleInstanceFlags) > 0 {
+				return fmt.Errorf("'%s' cannot be set when 'instances' are configured in the file", strings.Join(singleInstanceFlags, "', '"))
+			}
+			return nil
+		}
+		if argocdNamespace != "" {
+			// the URL, credentials, TLS, proxy and rate limit settings only apply to the Argo CD API server
+			apiServerFlags := []string{}
+			cmd.Flags().Visit(func(f *pflag.Flag) {
+				if (strings.HasPrefix(f.Name, "argocd-") || f.Name == "insecure") && !slices.Contains(kubeFlags, f.Name) {
+					apiServerFlags = append(apiServerFlags, "--"+f.Name)
+				}
+			})
+			if len(apiServerFlags) > 0 {
+				return fmt.Errorf("'%s' cannot be set along with '--argocd-namespace'", strings.Join(apiServerFlags, "', '"))
+			}
+			return nil
+		}
+		if argocdURL == "" {
+			return fmt.Errorf("the Argo CD URL or namespace must be set, or 'instances' must be configured in the file")
+		}
+		if err := config.ValidateCredentials(argocdToken, argocdTokenFile, argocdUsername, argocdPassword); err != nil {
+			return fmt.Errorf("invalid Argo CD credentials: %w", err)
+		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, _ []string) error {
@@ -57,32 +138,42 @@ var startServerCmd = &cobra.Command{
 		logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{
 			Level: lvl,
 		}))
-		logger.Info("starting the Argo CD MCP server", "transport", transport, "url", argocdURL, "insecure", argocdInsecure, "debug", debug)
+		logger.Info("starting the Argo CD MCP server", "transport", transport, "config", configFile, "url", argocdURL, "namespace", argocdNamespace, "insecure", argocdInsecure, "timeout", timeout, "max-retries", maxRetries, "cache-ttl", cacheTTL, "watch-applications", watchApplications, "debug", debug)
 		if debug {
 			lvl.Set(slog.LevelDebug)
 			logger.Debug("debug mode enabled")
 		}
-		cl := argocd.NewClient(argocdURL, argocdToken, argocdInsecure)
-		srv := server.New(logger, cl)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		instances, err := newInstances(ctx, logger)
+		if err != nil {
+			return err
+		}
+		logger.Info("configured the Argo CD instances", "instances", instances.Names())
+		srv, err := server.New(logger, instances, uncachedTools, retryMaxTime)
+		if err != nil {
+			return err
+		}
 		switch transport {
 		case "stdio":
 			t := &mcp.LoggingTransport{
 				Transport: &mcp.StdioTransport{},
 				Writer:    cmd.ErrOrStderr(),
 			}
-			if err := srv.Run(context.Background(), t); err != nil {
+			if err := srv.Run(ctx, t); err != nil {
 				return fmt.Errorf("failed to serve on stdio: %v", err.Error())
 			}
 		default:
 			handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
 				return srv
 			}, nil)
+			// no write timeout: a tool call may take longer than the timeout of a single request to Argo CD,
+			// and is bounded by the timeout and the retry settings instead (and canceled if the MCP client disconnects)
 			server := &http.Server{
-				Addr:         listen,
-				Handler:      handler,
-				ReadTimeout:  15 * time.Second,
-				WriteTimeout: 15 * time.Second,
-				IdleTimeout:  60 * time.Second,
+				Addr:        listen,
+				Handler:     handler,
+				ReadTimeout: 15 * time.Second,
+				IdleTimeout: 60 * time.Second,
 			}
 			if err := server.ListenAndServe(); err != nil {
 				return fmt.Errorf("failed to start server: %v", err.Error())
@@ -91,3 +182,147 @@ var startServerCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// newInstances returns the Argo CD instances configured in the file if any,
+// or a single 'default' instance from the '--argocd-url' and '--argocd-token[-file]' (or '--argocd-namespace') settings otherwise.
+// The timeout, retry, cache and watch settings apply to all the instances. The Applications are watched until the given context is done.
+func newInstances(ctx context.Context, logger *slog.Logger) (*argocd.Instances, error) {
+	retry := argocd.RetryOptions{
+		MaxRetries:     maxRetries,
+		InitialBackoff: retryInitialBackoff,
+		MaxBackoff:     retryMaxBackoff,
+	}
+	if len(cfg.Instances) == 0 && argocdNamespace != "" {
+		instance, err := newKubeInstance(ctx, "default", argocdNamespace, argocdKubeconfig, argocdKubeContext, logger)
+		if err != nil {
+			return nil, err
+		}
+		return argocd.NewInstances(instance)
+	}
+	if len(cfg.Instances) == 0 {
+		creds, err := newCredentials(argocdToken, argocdTokenFile, argocdUsername, argocdPassword)
+		if err != nil {
+			return nil, err
+		}
+		cl, err := argocd.NewClient(argocdURL, creds, argocd.ClientOptions{
+			TLS: argocd.TLSOptions{
+				Insecure:       argocdInsecure,
+				CAFile:         argocdCAFile,
+				ClientCertFile: argocdClientCert,
+				ClientKeyFile:  argocdClientKey,
+				MinVersion:     argocdTLSMinVersion,
+				ServerName:     argocdServerName,
+			},
+			BasePath: argocdBasePath,
+			Proxy:    argocdProxy,
+			Timeout:  timeout,
+			Retry:    retry,
+			RateLimit: argocd.RateLimitOptions{
+				QPS:         argocdRateLimit,
+				Burst:       argocdRateLimitBurst,
+				MaxInFlight: argocdMaxInFlight,
+			},
+			Logger: logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return argocd.NewInstances(argocd.Instance{
+			Name:   "default",
+			URL:    argocdURL,
+			Client: withCache(ctx, cl, logger),
+		})
+	}
+	instances := make([]argocd.Instance, 0, len(cfg.Instances))
+	for _, i := range cfg.Instances {
+		if i.Namespace != "" {
+			instance, err := newKubeInstance(ctx, i.Name, i.Namespace, i.Kubeconfig, i.KubeContext, logger.With("instance", i.Name))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Kubernetes settings of instance '%s': %w", i.Name, err)
+			}
+			instances = append(instances, instance)
+			continue
+		}
+		creds, err := newCredentials(i.Token, i.TokenFile, i.Username, i.Password)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credentials of instance '%s': %w", i.Name, err)
+		}
+		cl, err := argocd.NewClient(i.URL, creds, argocd.ClientOptions{
+			TLS: argocd.TLSOptions{
+				Insecure:       i.Insecure,
+				CAFile:         i.CAFile,
+				ClientCertFile: i.ClientCert,
+				ClientKeyFile:  i.ClientKey,
+				MinVersion:     i.TLSMinVersion,
+				ServerName:     i.ServerName,
+			},
+			BasePath: i.BasePath,
+			Proxy:    i.Proxy,
+			Timeout:  timeout,
+			Retry:    retry,
+			RateLimit: argocd.RateLimitOptions{
+				QPS:         i.RateLimit,
+				Burst:       i.RateLimitBurst,
+				MaxInFlight: i.MaxInFlight,
+			},
+			Logger: logger.With("instance", i.Name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid settings of instance '%s': %w", i.Name, err)
+		}
+		instances = append(instances, argocd.Instance{
+			Name:   i.Name,
+			URL:    i.URL,
+			Client: withCache(ctx, cl, logger.With("instance", i.Name)),
+		})
+	}
+	return argocd.NewInstances(instances...)
+}
+
+// newKubeInstance returns an instance which reads the Applications in the given namespace through the Kubernetes API,
+// with the given kubeconfig file and context (or the default ones)
+func newKubeInstance(ctx context.Context, name, namespace, kubeconfig, kubeContext string, logger *slog.Logger) (argocd.Instance, error) {
+	kubeCfg, err := argocd.NewKubeConfig(argocd.KubeOptions{
+		Kubeconfig: kubeconfig,
+		Context:    kubeContext,
+		Timeout:    timeout,
+	})
+	if err != nil {
+		return argocd.Instance{}, err
+	}
+	dyn, err := dynamic.NewForConfig(kubeCfg)
+	if err != nil {
+		return argocd.Instance{}, fmt.Errorf("failed to create the Kubernetes client: %w", err)
+	}
+	return argocd.Instance{
+		Name:          name,
+		URL:           argocd.KubeInstanceURL(kubeCfg, namespace),
+		Client:        withCache(ctx, argocd.NewKubeClient(dyn, namespace), logger),
+		KubernetesAPI: true,
+	}, nil
+}
+
+// newCredentials returns the credentials with the given static token, with the token read from the given file
+// (which is read again when it changes), or with a session token obtained by logging in with the given username and password
+func newCredentials(token, tokenFile, username, password string) (argocd.Credentials, error) {
+	switch {
+	case tokenFile != "":
+		return argocd.TokenFile(tokenFile)
+	case username != "":
+		return argocd.SessionLogin(username, password), nil
+	default:
+		return argocd.StaticToken(token), nil
+	}
+}
+
+// withCache returns a client which caches the responses of the given client, unless the cache is disabled,
+// and which answers the requests to list and get the Applications from an in-memory copy if they are watched
+func withCache(ctx context.Context, cl argocd.Client, logger *slog.Logger) argocd.Client {
+	if cacheTTL > 0 {
+		cl = argocd.NewCachedClient(cl, cacheTTL)
+	}
+	if watchApplications {
+		cl = argocd.NewWatchedClient(ctx, cl, logger)
+	}
+	return cl
+}
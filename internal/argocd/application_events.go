@@ -0,0 +1,172 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var ApplicationEventsTool = &mcp.Tool{
+	Name: "applicationEvents",
+	Description: "list the Kubernetes events of an Argo CD Application, or of one of its resources, from the oldest to the most recent. " +
+		"Warning events are the fastest way to find out why a resource is Progressing, Missing or Degraded",
+	InputSchema:  ApplicationEventsInputSchema,
+	OutputSchema: ApplicationEventsOutputSchema,
+}
+
+type ApplicationEventsInput struct {
+	Name              string `json:"name" jsonschema:"the name of the Argo CD Application to get the events of"`
+	AppNamespace      string `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance          string `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	Fresh             bool   `json:"fresh,omitempty" jsonschema:"bypass the cache and fetch the latest state from Argo CD (optional, only needed right after a change which was not made with this server)"`
+	ResourceName      string `json:"resourceName,omitempty" jsonschema:"the name of the resource to get the events of (instead of the events of the Application itself)"`
+	ResourceNamespace string `json:"resourceNamespace,omitempty" jsonschema:"the namespace of the resource to get the events of"`
+	ResourceUID       string `json:"resourceUID,omitempty" jsonschema:"the UID of the resource to get the events of"`
+}
+
+var ApplicationEventsInputSchema, _ = jsonschema.For[ApplicationEventsInput](&jsonschema.ForOptions{})
+
+type ApplicationEventsOutput ApplicationEvents
+
+var ApplicationEventsOutputSchema, _ = jsonschema.For[ApplicationEventsOutput](&jsonschema.ForOptions{})
+
+func ApplicationEventsToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[ApplicationEventsInput, ApplicationEventsOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in ApplicationEventsInput) (*mcp.CallToolResult, ApplicationEventsOutput, error) {
+		if in.Fresh {
+			ctx = WithoutCache(ctx)
+		}
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, ApplicationEventsOutput{}, err
+		}
+		events, err := listApplicationEvents(ctx, logger, cl, in)
+		if err != nil {
+			return nil, ApplicationEventsOutput{}, err
+		}
+		return nil, ApplicationEventsOutput(events), nil
+	}
+}
+
+type ApplicationEvents struct {
+	Events []Event `json:"events"`
+}
+
+// Event a Kubernetes event, aggregated with all the events with the same resource, reason and message
+type Event struct {
+	Type    string `json:"type"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Resource the `Kind/namespace/name` ID of the resource involved in the event
+	Resource       string `json:"resource"`
+	Count          int32  `json:"count"`
+	FirstTimestamp string `json:"firstTimestamp,omitempty"`
+	LastTimestamp  string `json:"lastTimestamp,omitempty"`
+}
+
+func listApplicationEvents(ctx context.Context, logger *slog.Logger, cl Client, in ApplicationEventsInput) (ApplicationEvents, error) {
+	ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+	if err != nil {
+		return ApplicationEvents{}, err
+	}
+	ref, err = resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return ApplicationEvents{}, err
+	}
+	events, err := cl.ListEvents(ctx, ref, ListEventsOptions{
+		ResourceName:      in.ResourceName,
+		ResourceNamespace: in.ResourceNamespace,
+		ResourceUID:       in.ResourceUID,
+	})
+	if err != nil {
+		return ApplicationEvents{}, fmt.Errorf("failed to list events of application '%s': %w", ref, err)
+	}
+
+	// aggregate the events with the same resource, reason and message
+	type aggregate struct {
+		event     Event
+		firstTime metav1.Time
+		lastTime  metav1.Time
+	}
+	aggregates := []*aggregate{}
+	index := map[string]*aggregate{}
+	for _, e := range events.Items {
+		resource := fmt.Sprintf("%s/%s/%s", e.InvolvedObject.Kind, e.InvolvedObject.Namespace, e.InvolvedObject.Name)
+		if e.InvolvedObject.Namespace == "" {
+			resource = fmt.Sprintf("%s/%s", e.InvolvedObject.Kind, e.InvolvedObject.Name)
+		}
+		count := e.Count
+		if count < 1 {
+			count = 1
+		}
+		firstTime, lastTime := eventTimes(e)
+		key := resource + "\n" + e.Reason + "\n" + e.Message
+		if a, found := index[key]; found {
+			a.event.Count += count
+			if firstTime.Before(&a.firstTime) {
+				a.firstTime = firstTime
+			}
+			if a.lastTime.Before(&lastTime) {
+				a.lastTime = lastTime
+				a.event.Type = e.Type
+			}
+			continue
+		}
+		a := &aggregate{
+			event: Event{
+				Type:     e.Type,
+				Reason:   e.Reason,
+				Message:  e.Message,
+				Resource: resource,
+				Count:    count,
+			},
+			firstTime: firstTime,
+			lastTime:  lastTime,
+		}
+		index[key] = a
+		aggregates = append(aggregates, a)
+	}
+	sort.SliceStable(aggregates, func(i, j int) bool {
+		return aggregates[i].lastTime.Before(&aggregates[j].lastTime)
+	})
+
+	result := ApplicationEvents{
+		Events: []Event{},
+	}
+	for _, a := range aggregates {
+		a.event.FirstTimestamp = formatTime(&a.firstTime)
+		a.event.LastTimestamp = formatTime(&a.lastTime)
+		result.Events = append(result.Events, a.event)
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		resultStr, err := json.Marshal(result)
+		if err != nil {
+			logger.Error("failed to convert application events to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "applicationEvents", "app", ref.String(), "result", string(resultStr))
+	}
+	return result, nil
+}
+
+// eventTimes returns the first and last times of the given event, falling back to its `eventTime`
+// (set by the newer `events.k8s.io` API) and then to its creation timestamp
+func eventTimes(e corev1.Event) (metav1.Time, metav1.Time) {
+	last := e.LastTimestamp
+	if last.IsZero() {
+		last = metav1.NewTime(e.EventTime.Time)
+	}
+	if last.IsZero() {
+		last = e.CreationTimestamp
+	}
+	first := e.FirstTimestamp
+	if first.IsZero() {
+		first = last
+	}
+	return first, last
+}
@@ -0,0 +1,103 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListApplicationEvents(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	successfulCreate := Event{
+		Type:           "Normal",
+		Reason:         "SuccessfulCreate",
+		Message:        "create Pod example-0 in StatefulSet example successful",
+		Resource:       "StatefulSet/example-ns/example",
+		Count:          1,
+		FirstTimestamp: "2025-08-07T13:48:04Z",
+		LastTimestamp:  "2025-08-07T13:48:04Z",
+	}
+	scheduled := Event{
+		Type:           "Normal",
+		Reason:         "Scheduled",
+		Message:        "Successfully assigned example-ns/example-0 to worker-1",
+		Resource:       "Pod/example-ns/example-0",
+		Count:          1,
+		FirstTimestamp: "2025-08-07T13:48:05Z", // from the `eventTime`
+		LastTimestamp:  "2025-08-07T13:48:05Z",
+	}
+	pulled := Event{
+		Type:           "Normal",
+		Reason:         "Pulled",
+		Message:        `Container image "quay.io/example/example:1.2.3" already present on machine`,
+		Resource:       "Pod/example-ns/example-0",
+		Count:          6,
+		FirstTimestamp: "2025-08-07T13:48:07Z",
+		LastTimestamp:  "2025-08-07T13:53:50Z",
+	}
+	backOff := Event{
+		Type:           "Warning",
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container example in pod example-0_example-ns(3e4f5a6b-7c8d-4e9f-0a1b-2c3d4e5f6a06)",
+		Resource:       "Pod/example-ns/example-0",
+		Count:          39, // 27 + 12
+		FirstTimestamp: "2025-08-07T13:48:30Z",
+		LastTimestamp:  "2025-08-07T14:01:15Z",
+	}
+
+	t.Run("all events", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		events, err := listApplicationEvents(context.Background(), logger, cl, ApplicationEventsInput{
+			Name: "example",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ApplicationEvents{
+			Events: []Event{successfulCreate, scheduled, pulled, backOff},
+		}, events)
+	})
+
+	t.Run("events of a resource", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		events, err := listApplicationEvents(context.Background(), logger, cl, ApplicationEventsInput{
+			Name:              "example",
+			ResourceName:      "example-0",
+			ResourceNamespace: "example-ns",
+			ResourceUID:       "3e4f5a6b-7c8d-4e9f-0a1b-2c3d4e5f6a06",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ApplicationEvents{
+			Events: []Event{scheduled, pulled, backOff},
+		}, events)
+	})
+
+	t.Run("argocd error", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := listApplicationEvents(context.Background(), logger, cl, ApplicationEventsInput{
+			Name: "example-error",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to get application 'example-error' from Argo CD")
+	})
+}
@@ -0,0 +1,136 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var ApplicationHistoryTool = &mcp.Tool{
+	Name:         "applicationHistory",
+	Description:  "list the deployment history of an Argo CD Application, from the oldest to the most recent deployment. The ID of an entry can be used to rollback the Application",
+	InputSchema:  ApplicationHistoryInputSchema,
+	OutputSchema: ApplicationHistoryOutputSchema,
+}
+
+type ApplicationHistoryInput struct {
+	Name         string `json:"name" jsonschema:"the name of the Argo CD Application to get the history of"`
+	AppNamespace string `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance     string `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	Fresh        bool   `json:"fresh,omitempty" jsonschema:"bypass the cache and fetch the latest state from Argo CD (optional, only needed right after a change which was not made with this server)"`
+}
+
+var ApplicationHistoryInputSchema, _ = jsonschema.For[ApplicationHistoryInput](&jsonschema.ForOptions{})
+
+type ApplicationHistoryOutput ApplicationHistory
+
+var ApplicationHistoryOutputSchema, _ = jsonschema.For[ApplicationHistoryOutput](&jsonschema.ForOptions{})
+
+func ApplicationHistoryToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[ApplicationHistoryInput, ApplicationHistoryOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in ApplicationHistoryInput) (*mcp.CallToolResult, ApplicationHistoryOutput, error) {
+		if in.Fresh {
+			ctx = WithoutCache(ctx)
+		}
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, ApplicationHistoryOutput{}, err
+		}
+		ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+		if err != nil {
+			return nil, ApplicationHistoryOutput{}, err
+		}
+		history, err := listApplicationHistory(ctx, logger, cl, ref)
+		if err != nil {
+			return nil, ApplicationHistoryOutput{}, err
+		}
+		return nil, ApplicationHistoryOutput(history), nil
+	}
+}
+
+type ApplicationHistory struct {
+	History []HistoryEntry `json:"history"`
+}
+
+// HistoryEntry a deployment of an application
+type HistoryEntry struct {
+	ID          int64           `json:"id"`
+	Revision    string          `json:"revision,omitempty"`
+	Revisions   []string        `json:"revisions,omitempty"`
+	DeployedAt  string          `json:"deployedAt"`
+	Source      *SourceSummary  `json:"source,omitempty"`
+	Sources     []SourceSummary `json:"sources,omitempty"`
+	InitiatedBy string          `json:"initiatedBy,omitempty"`
+}
+
+// SourceSummary the source of the manifests of an application
+type SourceSummary struct {
+	RepoURL        string `json:"repoURL"`
+	Path           string `json:"path,omitempty"`
+	Chart          string `json:"chart,omitempty"`
+	TargetRevision string `json:"targetRevision,omitempty"`
+}
+
+func listApplicationHistory(ctx context.Context, logger *slog.Logger, cl Client, ref ApplicationRef) (ApplicationHistory, error) {
+	ref, err := resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return ApplicationHistory{}, err
+	}
+	// the history cannot be projected by Argo CD when listing the applications
+	app, err := cl.GetApplication(ctx, ref)
+	if err != nil {
+		return ApplicationHistory{}, fmt.Errorf("failed to get application '%s' from Argo CD: %w", ref, err)
+	}
+	history := ApplicationHistory{
+		History: []HistoryEntry{},
+	}
+	for _, h := range app.Status.History {
+		entry := HistoryEntry{
+			ID:          h.ID,
+			Revision:    h.Revision,
+			Revisions:   h.Revisions,
+			DeployedAt:  formatTime(&h.DeployedAt),
+			InitiatedBy: initiatedBy(h.InitiatedBy),
+		}
+		if h.Source.RepoURL != "" {
+			s := newSourceSummary(h.Source)
+			entry.Source = &s
+		}
+		for _, s := range h.Sources {
+			entry.Sources = append(entry.Sources, newSourceSummary(s))
+		}
+		history.History = append(history.History, entry)
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		historyStr, err := json.Marshal(history)
+		if err != nil {
+			logger.Error("failed to convert application history to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "applicationHistory", "app", ref.String(), "result", string(historyStr))
+	}
+	return history, nil
+}
+
+func newSourceSummary(s argocdv3.ApplicationSource) SourceSummary {
+	return SourceSummary{
+		RepoURL:        s.RepoURL,
+		Path:           s.Path,
+		Chart:          s.Chart,
+		TargetRevision: s.TargetRevision,
+	}
+}
+
+func initiatedBy(i argocdv3.OperationInitiator) string {
+	switch {
+	case i.Username != "":
+		return i.Username
+	case i.Automated:
+		return "automated"
+	default:
+		return ""
+	}
+}
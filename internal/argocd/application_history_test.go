@@ -0,0 +1,66 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListApplicationHistory(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	t.Run("example", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		history, err := listApplicationHistory(context.Background(), logger, cl, ApplicationRef{Name: "example"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ApplicationHistory{
+			History: []HistoryEntry{
+				{
+					ID:         1,
+					Revision:   "3c1e9a4b5f6d7e8a9b0c1d2e3f4a5b6c7d8e9f01",
+					DeployedAt: "2025-08-06T09:12:41Z",
+					Source: &SourceSummary{
+						RepoURL:        "https://git/org/repo",
+						Path:           "components/example",
+						TargetRevision: "main",
+					},
+					InitiatedBy: "admin",
+				},
+				{
+					ID:         2,
+					Revision:   "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+					DeployedAt: "2025-08-07T13:40:12Z",
+					Source: &SourceSummary{
+						RepoURL:        "https://git/org/repo",
+						Path:           "components/example",
+						TargetRevision: "main",
+					},
+					InitiatedBy: "automated",
+				},
+			},
+		}, history)
+	})
+
+	t.Run("argocd error", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := listApplicationHistory(context.Background(), logger, cl, ApplicationRef{Name: "example-error"})
+
+		// then
+		require.ErrorContains(t, err, "failed to get application 'example-error' from Argo CD")
+	})
+}
@@ -0,0 +1,246 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var ApplicationResourceTreeTool = &mcp.Tool{
+	Name: "applicationResourceTree",
+	Description: "list the resources of an Argo CD Application along with the resources they own (eg: the ReplicaSets and Pods of a Deployment), " +
+		"with their health and their parent/child relationships. Use it to find the actual failing resources (eg: a crashing Pod) of an unhealthy resource",
+	InputSchema:  ApplicationResourceTreeInputSchema,
+	OutputSchema: ApplicationResourceTreeOutputSchema,
+}
+
+type ApplicationResourceTreeInput struct {
+	Name              string `json:"name" jsonschema:"the name of the Argo CD Application to get the resource tree of"`
+	AppNamespace      string `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance          string `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	Fresh             bool   `json:"fresh,omitempty" jsonschema:"bypass the cache and fetch the latest state from Argo CD (optional, only needed right after a change which was not made with this server)"`
+	UnhealthyOnly     bool   `json:"unhealthyOnly,omitempty" jsonschema:"only return the unhealthy resources and their ancestors"`
+	ResourceKind      string `json:"resourceKind,omitempty" jsonschema:"the kind of the resource to return the subtree of (eg: 'Deployment')"`
+	ResourceName      string `json:"resourceName,omitempty" jsonschema:"the name of the resource to return the subtree of"`
+	ResourceNamespace string `json:"resourceNamespace,omitempty" jsonschema:"the namespace of the resource to return the subtree of"`
+}
+
+var ApplicationResourceTreeInputSchema, _ = jsonschema.For[ApplicationResourceTreeInput](&jsonschema.ForOptions{})
+
+type ApplicationResourceTreeOutput ResourceTree
+
+var ApplicationResourceTreeOutputSchema, _ = jsonschema.For[ApplicationResourceTreeOutput](&jsonschema.ForOptions{})
+
+func ApplicationResourceTreeToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[ApplicationResourceTreeInput, ApplicationResourceTreeOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in ApplicationResourceTreeInput) (*mcp.CallToolResult, ApplicationResourceTreeOutput, error) {
+		if in.Fresh {
+			ctx = WithoutCache(ctx)
+		}
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, ApplicationResourceTreeOutput{}, err
+		}
+		tree, err := getApplicationResourceTree(ctx, logger, cl, in)
+		if err != nil {
+			return nil, ApplicationResourceTreeOutput{}, err
+		}
+		return nil, ApplicationResourceTreeOutput(tree), nil
+	}
+}
+
+// ResourceTree the resources of an application, in depth-first order
+type ResourceTree struct {
+	Nodes []ResourceTreeNode `json:"nodes"`
+}
+
+// ResourceTreeNode a resource in the tree, identified by its `Kind/namespace/name` ID
+type ResourceTreeNode struct {
+	ID        string         `json:"id"`
+	Group     string         `json:"group,omitempty"`
+	Version   string         `json:"version,omitempty"`
+	Kind      string         `json:"kind"`
+	Namespace string         `json:"namespace,omitempty"`
+	Name      string         `json:"name"`
+	Health    *HealthSummary `json:"health,omitempty"`
+	Info      []string       `json:"info,omitempty"`
+	Parents   []string       `json:"parents,omitempty"`
+	Children  []string       `json:"children,omitempty"`
+}
+
+func getApplicationResourceTree(ctx context.Context, logger *slog.Logger, cl Client, in ApplicationResourceTreeInput) (ResourceTree, error) {
+	ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+	if err != nil {
+		return ResourceTree{}, err
+	}
+	ref, err = resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return ResourceTree{}, err
+	}
+	t, err := cl.GetResourceTree(ctx, ref)
+	if err != nil {
+		return ResourceTree{}, fmt.Errorf("failed to get resource tree of application '%s': %w", ref, err)
+	}
+
+	// index the nodes and their relationships by node key
+	nodes := map[string]argocdv3.ResourceNode{}
+	for _, n := range t.Nodes {
+		nodes[nodeKey(n.ResourceRef)] = n
+	}
+	children := map[string][]string{}
+	parents := map[string][]string{}
+	roots := []string{}
+	for _, n := range t.Nodes {
+		k := nodeKey(n.ResourceRef)
+		hasParent := false
+		for _, p := range n.ParentRefs {
+			if _, found := nodes[nodeKey(p)]; found {
+				children[nodeKey(p)] = append(children[nodeKey(p)], k)
+				parents[k] = append(parents[k], nodeKey(p))
+				hasParent = true
+			}
+		}
+		if !hasParent {
+			roots = append(roots, k)
+		}
+	}
+	// start from the selected resource, if any
+	if in.ResourceKind != "" || in.ResourceName != "" {
+		roots = []string{}
+		for k, n := range nodes {
+			if (in.ResourceKind == "" || n.Kind == in.ResourceKind) &&
+				(in.ResourceName == "" || n.Name == in.ResourceName) &&
+				(in.ResourceNamespace == "" || n.Namespace == in.ResourceNamespace) {
+				roots = append(roots, k)
+			}
+		}
+		if len(roots) == 0 {
+			return ResourceTree{}, fmt.Errorf("no resource matching kind='%s', name='%s' and namespace='%s' in the tree of application '%s'", in.ResourceKind, in.ResourceName, in.ResourceNamespace, ref)
+		}
+	}
+	sortNodeKeys := func(keys []string) {
+		sort.Slice(keys, func(i, j int) bool {
+			return nodeID(nodes[keys[i]].ResourceRef) < nodeID(nodes[keys[j]].ResourceRef)
+		})
+	}
+	sortNodeKeys(roots)
+	for _, c := range children {
+		sortNodeKeys(c)
+	}
+
+	// retain the nodes reachable from the roots, or only the unhealthy ones and their ancestors if required.
+	// The ancestors are marked from the unhealthy nodes upwards, so that a node with several parents (or in a cycle)
+	// is retained along with all its parents, regardless of the order in which the nodes are visited.
+	reachable := map[string]bool{}
+	var reach func(k string)
+	reach = func(k string) {
+		if reachable[k] {
+			return
+		}
+		reachable[k] = true
+		for _, c := range children[k] {
+			reach(c)
+		}
+	}
+	for _, k := range roots {
+		reach(k)
+	}
+	retained := map[string]bool{}
+	var retain func(k string)
+	retain = func(k string) {
+		if retained[k] || !reachable[k] {
+			return
+		}
+		retained[k] = true
+		for _, p := range parents[k] {
+			retain(p)
+		}
+	}
+	for k := range reachable {
+		if n := nodes[k]; !in.UnhealthyOnly || (n.Health != nil && n.Health.Status != health.HealthStatusHealthy) {
+			retain(k)
+		}
+	}
+
+	// walk the tree in depth-first order
+	result := ResourceTree{
+		Nodes: []ResourceTreeNode{},
+	}
+	visited := map[string]bool{}
+	var walk func(k string)
+	walk = func(k string) {
+		if visited[k] || !retained[k] {
+			return
+		}
+		visited[k] = true
+		result.Nodes = append(result.Nodes, newResourceTreeNode(nodes[k], nodes, children[k], retained))
+		for _, c := range children[k] {
+			walk(c)
+		}
+	}
+	for _, k := range roots {
+		walk(k)
+	}
+
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		resultStr, err := json.Marshal(result)
+		if err != nil {
+			logger.Error("failed to convert resource tree to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "applicationResourceTree", "app", ref.String(), "result", string(resultStr))
+	}
+	return result, nil
+}
+
+func newResourceTreeNode(n argocdv3.ResourceNode, nodes map[string]argocdv3.ResourceNode, children []string, retained map[string]bool) ResourceTreeNode {
+	node := ResourceTreeNode{
+		ID:        nodeID(n.ResourceRef),
+		Group:     n.Group,
+		Version:   n.Version,
+		Kind:      n.Kind,
+		Namespace: n.Namespace,
+		Name:      n.Name,
+	}
+	if n.Health != nil {
+		node.Health = &HealthSummary{
+			Status:  string(n.Health.Status),
+			Message: n.Health.Message,
+		}
+	}
+	for _, i := range n.Info {
+		node.Info = append(node.Info, fmt.Sprintf("%s: %s", i.Name, i.Value))
+	}
+	for _, p := range n.ParentRefs {
+		if retained[nodeKey(p)] {
+			node.Parents = append(node.Parents, nodeID(p))
+		}
+	}
+	for _, c := range children {
+		if retained[c] {
+			node.Children = append(node.Children, nodeID(nodes[c].ResourceRef))
+		}
+	}
+	return node
+}
+
+// nodeKey returns the key of the resource in the tree: its UID, or its ID if it has no UID
+func nodeKey(r argocdv3.ResourceRef) string {
+	if r.UID != "" {
+		return r.UID
+	}
+	return nodeID(r)
+}
+
+// nodeID returns the `Kind/namespace/name` (or `Kind/name` for cluster-scoped resources) ID of the resource
+func nodeID(r argocdv3.ResourceRef) string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+}
@@ -0,0 +1,232 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetApplicationResourceTree(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	statefulSet := ResourceTreeNode{
+		ID:        "StatefulSet/example-ns/example",
+		Group:     "apps",
+		Version:   "v1",
+		Kind:      "StatefulSet",
+		Namespace: "example-ns",
+		Name:      "example",
+		Health: &HealthSummary{
+			Status:  "Progressing",
+			Message: "Waiting for 1 pods to be ready...",
+		},
+		Children: []string{"ControllerRevision/example-ns/example-5d4f8b9c6", "Pod/example-ns/example-0"},
+	}
+	controllerRevision := ResourceTreeNode{
+		ID:        "ControllerRevision/example-ns/example-5d4f8b9c6",
+		Group:     "apps",
+		Version:   "v1",
+		Kind:      "ControllerRevision",
+		Namespace: "example-ns",
+		Name:      "example-5d4f8b9c6",
+		Parents:   []string{"StatefulSet/example-ns/example"},
+	}
+	pod := ResourceTreeNode{
+		ID:        "Pod/example-ns/example-0",
+		Version:   "v1",
+		Kind:      "Pod",
+		Namespace: "example-ns",
+		Name:      "example-0",
+		Health: &HealthSummary{
+			Status:  "Degraded",
+			Message: "back-off 5m0s restarting failed container=example pod=example-0_example-ns(3e4f5a6b-7c8d-4e9f-0a1b-2c3d4e5f6a06)",
+		},
+		Info:    []string{"Status Reason: CrashLoopBackOff", "Node: worker-1", "Containers: 0/1", "Restart Count: 5"},
+		Parents: []string{"StatefulSet/example-ns/example"},
+	}
+	configMap := ResourceTreeNode{
+		ID:        "ConfigMap/example-ns/example-config",
+		Version:   "v1",
+		Kind:      "ConfigMap",
+		Namespace: "example-ns",
+		Name:      "example-config",
+	}
+	service := ResourceTreeNode{
+		ID:        "Service/example-ns/example-http",
+		Version:   "v1",
+		Kind:      "Service",
+		Namespace: "example-ns",
+		Name:      "example-http",
+		Health: &HealthSummary{
+			Status: "Healthy",
+		},
+		Children: []string{"EndpointSlice/example-ns/example-http-x7k2p"},
+	}
+	endpointSlice := ResourceTreeNode{
+		ID:        "EndpointSlice/example-ns/example-http-x7k2p",
+		Group:     "discovery.k8s.io",
+		Version:   "v1",
+		Kind:      "EndpointSlice",
+		Namespace: "example-ns",
+		Name:      "example-http-x7k2p",
+		Parents:   []string{"Service/example-ns/example-http"},
+	}
+
+	t.Run("all resources", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		tree, err := getApplicationResourceTree(context.Background(), logger, cl, ApplicationResourceTreeInput{
+			Name: "example",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ResourceTree{
+			Nodes: []ResourceTreeNode{configMap, service, endpointSlice, statefulSet, controllerRevision, pod},
+		}, tree)
+	})
+
+	t.Run("unhealthy resources only", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		tree, err := getApplicationResourceTree(context.Background(), logger, cl, ApplicationResourceTreeInput{
+			Name:          "example",
+			UnhealthyOnly: true,
+		})
+
+		// then
+		require.NoError(t, err)
+		statefulSet := statefulSet
+		statefulSet.Children = []string{"Pod/example-ns/example-0"}
+		assert.Equal(t, ResourceTree{
+			Nodes: []ResourceTreeNode{statefulSet, pod},
+		}, tree)
+	})
+
+	t.Run("subtree of a resource", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		tree, err := getApplicationResourceTree(context.Background(), logger, cl, ApplicationResourceTreeInput{
+			Name:         "example",
+			ResourceKind: "Service",
+			ResourceName: "example-http",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ResourceTree{
+			Nodes: []ResourceTreeNode{service, endpointSlice},
+		}, tree)
+	})
+
+	t.Run("unknown resource", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := getApplicationResourceTree(context.Background(), logger, cl, ApplicationResourceTreeInput{
+			Name:         "example",
+			ResourceKind: "Deployment",
+		})
+
+		// then
+		require.EqualError(t, err, "no resource matching kind='Deployment', name='' and namespace='' in the tree of application 'argocd/example'")
+	})
+
+	t.Run("unhealthy resources with several parents", func(t *testing.T) {
+		// given
+		node := func(kind, name string, status health.HealthStatusCode, parents ...argocdv3.ResourceRef) argocdv3.ResourceNode {
+			return argocdv3.ResourceNode{
+				ResourceRef: argocdv3.ResourceRef{Kind: kind, Namespace: "example-ns", Name: name},
+				ParentRefs:  parents,
+				Health:      &argocdv3.HealthStatus{Status: status},
+			}
+		}
+		ref := func(kind, name string) argocdv3.ResourceRef {
+			return argocdv3.ResourceRef{Kind: kind, Namespace: "example-ns", Name: name}
+		}
+		cl := &treeArgoCDClient{
+			tree: &argocdv3.ApplicationTree{
+				Nodes: []argocdv3.ResourceNode{
+					// a child shared by 2 parents
+					node("Deployment", "a", health.HealthStatusHealthy),
+					node("Deployment", "b", health.HealthStatusHealthy),
+					node("ReplicaSet", "shared", health.HealthStatusHealthy, ref("Deployment", "a"), ref("Deployment", "b")),
+					node("Pod", "shared-0", health.HealthStatusDegraded, ref("ReplicaSet", "shared")),
+					// a cycle, in which `Bar/c` is visited before the unhealthy `Pod/c`
+					node("Service", "c", health.HealthStatusHealthy),
+					node("Foo", "c", health.HealthStatusHealthy, ref("Service", "c"), ref("Bar", "c")),
+					node("Bar", "c", health.HealthStatusHealthy, ref("Foo", "c")),
+					node("Pod", "c", health.HealthStatusDegraded, ref("Foo", "c")),
+					// a healthy resource which is not retained
+					node("ConfigMap", "d", health.HealthStatusHealthy, ref("Deployment", "a")),
+				},
+			},
+		}
+
+		// when
+		tree, err := getApplicationResourceTree(context.Background(), logger, cl, ApplicationResourceTreeInput{
+			Name:          "example",
+			UnhealthyOnly: true,
+		})
+
+		// then
+		require.NoError(t, err)
+		ids := []string{}
+		parents := map[string][]string{}
+		for _, n := range tree.Nodes {
+			ids = append(ids, n.ID)
+			parents[n.ID] = n.Parents
+		}
+		assert.Equal(t, []string{
+			"Deployment/example-ns/a",
+			"ReplicaSet/example-ns/shared",
+			"Pod/example-ns/shared-0",
+			"Deployment/example-ns/b",
+			"Service/example-ns/c",
+			"Foo/example-ns/c",
+			"Bar/example-ns/c",
+			"Pod/example-ns/c",
+		}, ids)
+		assert.Equal(t, []string{"Deployment/example-ns/a", "Deployment/example-ns/b"}, parents["ReplicaSet/example-ns/shared"])
+		assert.Equal(t, []string{"Service/example-ns/c", "Bar/example-ns/c"}, parents["Foo/example-ns/c"])
+	})
+
+	t.Run("argocd error", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := getApplicationResourceTree(context.Background(), logger, cl, ApplicationResourceTreeInput{
+			Name: "example-error",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to get application 'example-error' from Argo CD")
+	})
+}
+
+// treeArgoCDClient returns the given resource tree
+type treeArgoCDClient struct {
+	FakeArgoCDClient
+	tree *argocdv3.ApplicationTree
+}
+
+func (c *treeArgoCDClient) GetResourceTree(_ context.Context, _ ApplicationRef) (*argocdv3.ApplicationTree, error) {
+	return c.tree, nil
+}
@@ -1,43 +1,664 @@
 package argocd
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
+// Client is a typed client for the Argo CD API server
 type Client interface {
-	GetWithContext(ctx context.Context, path string) (*http.Response, error)
+	ListApplications(ctx context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error)
+	GetApplication(ctx context.Context, app ApplicationRef) (*argocdv3.Application, error)
+	RefreshApplication(ctx context.Context, app ApplicationRef, refresh argocdv3.RefreshType) (*argocdv3.Application, error)
+	GetResourceTree(ctx context.Context, app ApplicationRef) (*argocdv3.ApplicationTree, error)
+	GetManagedResources(ctx context.Context, app ApplicationRef) ([]*argocdv3.ResourceDiff, error)
+	ListEvents(ctx context.Context, app ApplicationRef, opts ListEventsOptions) (*corev1.EventList, error)
+	StreamPodLogs(ctx context.Context, app ApplicationRef, opts PodLogsOptions, handle func(LogEntry) bool) error
+	WatchApplications(ctx context.Context, resourceVersion string, handle func(argocdv3.ApplicationWatchEvent) bool) error
+	ListProjects(ctx context.Context) (*argocdv3.AppProjectList, error)
+	ListClusters(ctx context.Context) (*argocdv3.ClusterList, error)
+	SyncApplication(ctx context.Context, app ApplicationRef, req SyncApplicationRequest) (*argocdv3.Application, error)
+	RollbackApplication(ctx context.Context, app ApplicationRef, req RollbackApplicationRequest) (*argocdv3.Application, error)
+}
+
+// ApplicationRef the reference to an application, by name and namespace. The namespace is optional,
+// in which case Argo CD looks up the application in its own namespace (eg: `argocd`).
+type ApplicationRef struct {
+	Name      string
+	Namespace string
+}
+
+// NewApplicationRef returns the reference to the application with the given name, which can be
+// qualified with its namespace (`namespace/name`), and the given namespace (optional).
+// Returns an error if the name is qualified with another namespace than the given one.
+func NewApplicationRef(name string, namespace string) (ApplicationRef, error) {
+	if ns, n, found := strings.Cut(name, "/"); found {
+		if namespace != "" && namespace != ns {
+			return ApplicationRef{}, fmt.Errorf("the namespace of the application name '%s' does not match the application namespace '%s'", name, namespace)
+		}
+		name = n
+		namespace = ns
+	}
+	return ApplicationRef{
+		Name:      name,
+		Namespace: namespace,
+	}, nil
+}
+
+// String returns the namespace-qualified name of the application (`namespace/name`),
+// or its name only if the namespace is not set
+func (r ApplicationRef) String() string {
+	if r.Namespace == "" {
+		return r.Name
+	}
+	return r.Namespace + "/" + r.Name
+}
+
+// addQuery adds the `appNamespace` query param (if the namespace is set) to the given query params
+func (r ApplicationRef) addQuery(q url.Values) url.Values {
+	if q == nil {
+		q = url.Values{}
+	}
+	if r.Namespace != "" {
+		q.Set("appNamespace", r.Namespace)
+	}
+	return q
+}
+
+// ListApplicationsOptions the options to filter the applications returned by Argo CD
+type ListApplicationsOptions struct {
+	// Name the name of the application (optional)
+	Name string
+	// Projects the projects of the applications (optional)
+	Projects []string
+	// Selector the label selector of the applications (optional)
+	Selector string
+	// Repo the URL of the source repository of the applications (optional)
+	Repo string
+	// AppNamespace the namespace of the applications (optional)
+	AppNamespace string
+	// Fields the fields to return, as paths in the application list (eg: `items.status.health`), to reduce the size
+	// of the response (optional, all the fields are returned by default). Only the API server takes them into account.
+	Fields []string
+}
+
+func (o ListApplicationsOptions) query() url.Values {
+	q := url.Values{}
+	if o.Name != "" {
+		q.Set("name", o.Name)
+	}
+	for _, p := range o.Projects {
+		q.Add("projects", p)
+	}
+	if o.Selector != "" {
+		q.Set("selector", o.Selector)
+	}
+	if o.Repo != "" {
+		q.Set("repo", o.Repo)
+	}
+	if o.AppNamespace != "" {
+		q.Set("appNamespace", o.AppNamespace)
+	}
+	if len(o.Fields) > 0 {
+		q.Set("fields", strings.Join(o.Fields, ","))
+	}
+	return q
+}
+
+// matches returns true if the given application matches the options, in the same way as the Argo CD API server
+// filters the applications (the selector is parsed by the caller, only once for all the applications)
+func (o ListApplicationsOptions) matches(app *argocdv3.Application, selector labels.Selector) bool {
+	if (o.Name != "" && app.Name != o.Name) ||
+		(len(o.Projects) > 0 && !slices.Contains(o.Projects, app.Spec.Project)) ||
+		!selector.Matches(labels.Set(app.Labels)) ||
+		(o.AppNamespace != "" && app.Namespace != o.AppNamespace) {
+		return false
+	}
+	if o.Repo == "" {
+		return true
+	}
+	if app.Spec.Source != nil && app.Spec.Source.RepoURL == o.Repo {
+		return true
+	}
+	return slices.ContainsFunc(app.Spec.Sources, func(s argocdv3.ApplicationSource) bool {
+		return s.RepoURL == o.Repo
+	})
+}
+
+// ListEventsOptions the options to select the resource whose events are returned by Argo CD.
+// If no option is set, the events of the application itself are returned.
+type ListEventsOptions struct {
+	// ResourceName the name of the resource (optional)
+	ResourceName string
+	// ResourceNamespace the namespace of the resource (optional)
+	ResourceNamespace string
+	// ResourceUID the UID of the resource (optional)
+	ResourceUID string
+}
+
+func (o ListEventsOptions) query() url.Values {
+	q := url.Values{}
+	if o.ResourceName != "" {
+		q.Set("resourceName", o.ResourceName)
+	}
+	if o.ResourceNamespace != "" {
+		q.Set("resourceNamespace", o.ResourceNamespace)
+	}
+	if o.ResourceUID != "" {
+		q.Set("resourceUID", o.ResourceUID)
+	}
+	return q
+}
+
+// PodLogsOptions the options to select the logs returned by Argo CD.
+// Either the `PodName` or the `Kind` and `ResourceName` of a resource (eg: a Deployment) must be set.
+type PodLogsOptions struct {
+	// PodName the name of the pod (optional)
+	PodName string
+	// Group the API group of the resource (optional)
+	Group string
+	// Kind the kind of the resource (optional)
+	Kind string
+	// ResourceName the name of the resource (optional)
+	ResourceName string
+	// Namespace the namespace of the pod or resource (optional)
+	Namespace string
+	// Container the name of the container (optional)
+	Container string
+	// TailLines the number of lines to return from the end of the logs (optional)
+	TailLines int64
+	// SinceSeconds only return the logs newer than this number of seconds (optional)
+	SinceSeconds int64
+	// Previous return the logs of the previous (terminated) container
+	Previous bool
+	// Filter only return the lines which contain this string (optional)
+	Filter string
+}
+
+func (o PodLogsOptions) query() url.Values {
+	q := url.Values{}
+	q.Set("follow", "false")
+	for k, v := range map[string]string{
+		"podName":      o.PodName,
+		"group":        o.Group,
+		"kind":         o.Kind,
+		"resourceName": o.ResourceName,
+		"namespace":    o.Namespace,
+		"container":    o.Container,
+		"filter":       o.Filter,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if o.TailLines > 0 {
+		q.Set("tailLines", strconv.FormatInt(o.TailLines, 10))
+	}
+	if o.SinceSeconds > 0 {
+		q.Set("sinceSeconds", strconv.FormatInt(o.SinceSeconds, 10))
+	}
+	if o.Previous {
+		q.Set("previous", "true")
+	}
+	return q
+}
+
+// LogEntry a line of logs of a pod
+// (see `LogEntry` in https://github.com/argoproj/argo-cd/blob/v3.0.19/server/application/application.proto)
+type LogEntry struct {
+	Content      string `json:"content"`
+	PodName      string `json:"podName"`
+	TimeStampStr string `json:"timeStampStr"`
+	// Last true for the (empty) entry which marks the end of the logs
+	Last bool `json:"last"`
+}
+
+// SyncApplicationRequest the body of the request to sync an application
+// (see `ApplicationSyncRequest` in https://github.com/argoproj/argo-cd/blob/v3.0.19/server/application/application.proto)
+type SyncApplicationRequest struct {
+	AppNamespace string                           `json:"appNamespace,omitempty"`
+	Revision     string                           `json:"revision,omitempty"`
+	DryRun       bool                             `json:"dryRun,omitempty"`
+	Prune        bool                             `json:"prune,omitempty"`
+	Strategy     *argocdv3.SyncStrategy           `json:"strategy,omitempty"`
+	Resources    []argocdv3.SyncOperationResource `json:"resources,omitempty"`
+	SyncOptions  *SyncOptions                     `json:"syncOptions,omitempty"`
+}
+
+// SyncOptions the sync options of a SyncApplicationRequest (eg: `ServerSideApply=true`)
+type SyncOptions struct {
+	Items []string `json:"items"`
+}
+
+// RollbackApplicationRequest the body of the request to rollback an application
+// (see `ApplicationRollbackRequest` in https://github.com/argoproj/argo-cd/blob/v3.0.19/server/application/application.proto)
+type RollbackApplicationRequest struct {
+	AppNamespace string `json:"appNamespace,omitempty"`
+	ID           int64  `json:"id"`
+	DryRun       bool   `json:"dryRun,omitempty"`
+	Prune        bool   `json:"prune,omitempty"`
+}
+
+// APIError the error returned when the Argo CD API server responds with an unexpected status.
+// The code and message are decoded from the gRPC-gateway error in the response body, when possible.
+type APIError struct {
+	// StatusCode the HTTP status code of the response
+	StatusCode int
+	// Code the gRPC status code
+	Code int
+	// Message the error message, or the raw response body if it could not be decoded
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected Argo CD status %d: %s", e.StatusCode, e.Message)
+}
+
+func newAPIError(statusCode int, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: statusCode,
+		Message:    string(body),
+	}
+	// see https://github.com/grpc-ecosystem/grpc-gateway/blob/v1.16.0/runtime/errors.go
+	grpcErr := struct {
+		Error   string `json:"error"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &grpcErr); err == nil {
+		e.Code = grpcErr.Code
+		switch {
+		case grpcErr.Message != "":
+			e.Message = grpcErr.Message
+		case grpcErr.Error != "":
+			e.Message = grpcErr.Error
+		}
+	}
+	return e
+}
+
+// IsNotFound returns true if the given error is an APIError with a '404 Not Found' status
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized returns true if the given error is an APIError with a '401 Unauthorized' status
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+func hasStatus(err error, statusCode int) bool {
+	apiErr := &APIError{}
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
 }
 
 type client struct {
 	*http.Client
-	host  string
-	token string
+	// stream the HTTP client for the long-lived streams, which shares the transport of the client but has no timeout
+	stream  *http.Client
+	base    *url.URL
+	creds   Credentials
+	retry   RetryOptions
+	limiter *limiter
+	logger  *slog.Logger
 }
 
-func NewClient(host string, token string, insecure bool) Client {
-	cl := http.DefaultClient
-	if insecure {
-		cl.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: insecure, //nolint:gosec
-			},
-		}
+// NewClient returns a new client to query the Argo CD instance at the given URL with the given credentials
+func NewClient(host string, creds Credentials, opts ClientOptions) (Client, error) {
+	base, err := opts.baseURL(host)
+	if err != nil {
+		return nil, err
+	}
+	cl, err := newHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := creds.(binder); ok {
+		b.bind(base, cl)
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	l, err := newLimiter(opts.RateLimit, logger)
+	if err != nil {
+		return nil, err
 	}
 	return &client{
 		Client: cl,
-		host:   host,
-		token:  token,
+		stream: &http.Client{
+			Transport: cl.Transport,
+		},
+		base:    base,
+		creds:   creds,
+		retry:   opts.Retry,
+		limiter: l,
+		logger:  logger,
+	}, nil
+}
+
+// do sends a request on the given path (no heading `/`) with the bearer token, once the rate limit (if any) allows it.
+// If the request is idempotent and failed with a transient error, then it is sent again with an exponential backoff.
+func (c *client) do(ctx context.Context, method string, path string, contentType string, body io.Reader) (*http.Response, error) {
+	// read the body upfront, so that it can be sent again
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = io.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("failed to read HTTP request body: %w", err)
+		}
+	}
+	for retry := 1; ; retry++ {
+		release, err := c.limiter.wait(ctx, method, path)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.sendWithCredentials(ctx, c.Client, method, path, contentType, data, body != nil)
+		if err != nil {
+			release()
+		} else {
+			// the request is in flight until its response body is closed
+			resp.Body = &releasingBody{
+				ReadCloser: resp.Body,
+				release:    release,
+			}
+		}
+		delay, ok := c.retry.retryDelay(method, retry, resp, err)
+		if !ok || ctx.Err() != nil {
+			return resp, err
+		}
+		if deadline, found := retryDeadline(ctx); found && time.Now().Add(delay).After(deadline) {
+			c.logger.WarnContext(ctx, "not retrying request to Argo CD after the retry deadline", "method", method, "path", path, "retry", retry, "delay", delay.String())
+			return resp, err
+		}
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		c.logger.WarnContext(ctx, "retrying request to Argo CD", "method", method, "path", path, "retry", retry, "delay", delay.String(), "reason", reason)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
 }
 
-func (c *client) GetWithContext(ctx context.Context, path string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%s", c.host, path), nil)
+// sendWithCredentials sends the request with the bearer token. If Argo CD rejects the token
+// and the credentials provide a fresh one, then the request is sent again once.
+func (c *client) sendWithCredentials(ctx context.Context, hc *http.Client, method string, path string, contentType string, data []byte, hasBody bool) (*http.Response, error) {
+	token, err := c.creds.Token(ctx)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
-	return c.Do(req)
+	resp, err := c.send(ctx, hc, method, path, contentType, data, hasBody, token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.creds.Invalidate(ctx, token) {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if token, err = c.creds.Token(ctx); err != nil {
+			return nil, err
+		}
+		return c.send(ctx, hc, method, path, contentType, data, hasBody, token)
+	}
+	return resp, nil
+}
+
+func (c *client) send(ctx context.Context, hc *http.Client, method string, path string, contentType string, data []byte, hasBody bool, token string) (*http.Response, error) {
+	var body io.Reader
+	if hasBody {
+		body = bytes.NewReader(data)
+	}
+	u, err := resolve(c.base, path)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	if hasBody && contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return hc.Do(req)
+}
+
+func (c *client) ListApplications(ctx context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	apps := &argocdv3.ApplicationList{}
+	if err := c.get(ctx, "api/v1/applications", opts.query(), apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+func (c *client) GetApplication(ctx context.Context, ref ApplicationRef) (*argocdv3.Application, error) {
+	app := &argocdv3.Application{}
+	if err := c.get(ctx, applicationPath(ref), ref.addQuery(nil), app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// RefreshApplication gets the application after Argo CD refreshed it
+// (the Argo CD API server waits for the refresh to complete before responding)
+func (c *client) RefreshApplication(ctx context.Context, ref ApplicationRef, refresh argocdv3.RefreshType) (*argocdv3.Application, error) {
+	app := &argocdv3.Application{}
+	if err := c.get(ctx, applicationPath(ref), ref.addQuery(url.Values{"refresh": []string{string(refresh)}}), app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func (c *client) GetResourceTree(ctx context.Context, ref ApplicationRef) (*argocdv3.ApplicationTree, error) {
+	tree := &argocdv3.ApplicationTree{}
+	if err := c.get(ctx, applicationPath(ref, "resource-tree"), ref.addQuery(nil), tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (c *client) GetManagedResources(ctx context.Context, ref ApplicationRef) ([]*argocdv3.ResourceDiff, error) {
+	resources := &struct {
+		Items []*argocdv3.ResourceDiff `json:"items"`
+	}{}
+	if err := c.get(ctx, applicationPath(ref, "managed-resources"), ref.addQuery(nil), resources); err != nil {
+		return nil, err
+	}
+	return resources.Items, nil
+}
+
+func (c *client) ListEvents(ctx context.Context, ref ApplicationRef, opts ListEventsOptions) (*corev1.EventList, error) {
+	events := &corev1.EventList{}
+	if err := c.get(ctx, applicationPath(ref, "events"), ref.addQuery(opts.query()), events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// StreamPodLogs reads the logs of the selected pod(s) of the application, and calls the given handle
+// func for each log entry, until the end of the logs or until the handle func returns `false`
+func (c *client) StreamPodLogs(ctx context.Context, ref ApplicationRef, opts PodLogsOptions, handle func(LogEntry) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // also interrupts the stream if the handle func stopped reading early
+	resp, err := c.do(ctx, http.MethodGet, applicationPath(ref, "logs")+"?"+ref.addQuery(opts.query()).Encode(), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read HTTP response body: %w", err)
+		}
+		return newAPIError(resp.StatusCode, data)
+	}
+	// the last entry has no content
+	return decodeStreamResults(resp.Body, "log entry", func(e LogEntry) bool {
+		return !e.Last && handle(e)
+	})
+}
+
+// WatchApplications streams the changes of the applications after the given resource version (or all the applications
+// as `ADDED` events if the resource version is empty), and calls the given handle func for each event, until the stream
+// is closed by the server, the context is done, or the handle func returns `false`. The request is not retried.
+func (c *client) WatchApplications(ctx context.Context, resourceVersion string, handle func(argocdv3.ApplicationWatchEvent) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // also interrupts the stream if the handle func stopped reading early
+	path := "api/v1/stream/applications"
+	if resourceVersion != "" {
+		path += "?" + url.Values{"resourceVersion": []string{resourceVersion}}.Encode()
+	}
+	resp, err := c.sendWithCredentials(ctx, c.stream, http.MethodGet, path, "", nil, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read HTTP response body: %w", err)
+		}
+		return newAPIError(resp.StatusCode, data)
+	}
+	return decodeStreamResults(resp.Body, "application event", handle)
+}
+
+// decodeStreamResults decodes the newline-delimited JSON stream of results returned by Argo CD (eg: log entries or application events),
+// one result at a time, until the end of the stream or until the handle func returns `false`. The given name of the results is used in
+// the decoding errors.
+func decodeStreamResults[T any](r io.Reader, name string, handle func(T) bool) error {
+	dec := json.NewDecoder(r)
+	for {
+		chunk := struct {
+			Result *T `json:"result"`
+			Error  *struct {
+				HTTPCode int    `json:"http_code"`
+				Message  string `json:"message"`
+			} `json:"error"`
+		}{}
+		if err := dec.Decode(&chunk); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+		switch {
+		case chunk.Error != nil:
+			return &APIError{
+				StatusCode: chunk.Error.HTTPCode,
+				Message:    chunk.Error.Message,
+			}
+		case chunk.Result == nil:
+			continue
+		}
+		if !handle(*chunk.Result) {
+			return nil
+		}
+	}
+}
+
+func (c *client) ListProjects(ctx context.Context) (*argocdv3.AppProjectList, error) {
+	projects := &argocdv3.AppProjectList{}
+	if err := c.get(ctx, "api/v1/projects", nil, projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (c *client) ListClusters(ctx context.Context) (*argocdv3.ClusterList, error) {
+	clusters := &argocdv3.ClusterList{}
+	if err := c.get(ctx, "api/v1/clusters", nil, clusters); err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+func (c *client) SyncApplication(ctx context.Context, ref ApplicationRef, req SyncApplicationRequest) (*argocdv3.Application, error) {
+	app := &argocdv3.Application{}
+	req.AppNamespace = ref.Namespace
+	if err := c.call(ctx, http.MethodPost, applicationPath(ref, "sync"), nil, req, app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func (c *client) RollbackApplication(ctx context.Context, ref ApplicationRef, req RollbackApplicationRequest) (*argocdv3.Application, error) {
+	app := &argocdv3.Application{}
+	req.AppNamespace = ref.Namespace
+	if err := c.call(ctx, http.MethodPost, applicationPath(ref, "rollback"), nil, req, app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// get sends a GET request on the given path (no heading `/`) and query params,
+// and unmarshals the response body into the given result
+func (c *client) get(ctx context.Context, path string, query url.Values, result any) error {
+	return c.call(ctx, http.MethodGet, path, query, nil, result)
+}
+
+// call sends a request on the given path (no heading `/`) and query params, with the
+// given payload (if not nil) as its JSON body, and decodes the response body into the given result
+// while reading it, so that large responses (eg: lists of applications) are not held in memory twice
+func (c *client) call(ctx context.Context, method string, path string, query url.Values, payload any, result any) error {
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	resp, err := c.do(ctx, method, path, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read HTTP response body: %w", err)
+		}
+		return newAPIError(resp.StatusCode, data)
+	}
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// applicationPath returns the path of the given application,
+// optionally followed by the given sub-resource elements, all escaped
+func applicationPath(ref ApplicationRef, elems ...string) string {
+	path := "api/v1/applications/" + url.PathEscape(ref.Name)
+	for _, e := range elems {
+		path = path + "/" + url.PathEscape(e)
+	}
+	return path
 }
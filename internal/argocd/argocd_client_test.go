@@ -0,0 +1,362 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	testresources "github.com/codeready-toolchain/argocd-mcp/test/resources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+
+	// a minimal Argo CD server which records the requested URI
+	var requestURI string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secure-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/api/v1/applications":
+			_, _ = w.Write([]byte(testresources.ExampleApplicationStr))
+		case r.URL.Path == "/api/v1/applications/example":
+			_, _ = w.Write([]byte(`{"metadata":{"name":"example","namespace":"argocd"}}`))
+		case r.URL.Path == "/api/v1/applications/example/managed-resources":
+			_, _ = w.Write([]byte(`{"items":[{"kind":"Service","name":"example"}]}`))
+		case r.URL.Path == "/api/v1/applications/example/events":
+			_, _ = w.Write([]byte(`{"items":[{"reason":"BackOff","involvedObject":{"kind":"Pod","name":"example-0"}}]}`))
+		case r.URL.Path == "/api/v1/applications/example/logs":
+			_, _ = w.Write([]byte(testresources.ExamplePodLogsStr))
+		case r.URL.Path == "/api/v1/applications/example-error/logs":
+			_, _ = w.Write([]byte(`{"error":{"grpc_code":5,"http_code":404,"message":"pods \"unknown\" not found","http_status":"Not Found"}}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("list applications", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		apps, err := cl.ListApplications(context.Background(), ListApplicationsOptions{
+			Name: "example&foo=bar",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/applications?name=example%26foo%3Dbar", requestURI)
+		require.Len(t, apps.Items, 1)
+		assert.Equal(t, "example", apps.Items[0].Name)
+	})
+
+	t.Run("list applications with filters", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{
+			Projects:     []string{"team-a", "team-b"},
+			Selector:     "team=a",
+			Repo:         "https://github.com/example/apps.git",
+			AppNamespace: "argocd",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/applications?appNamespace=argocd&projects=team-a&projects=team-b&repo=https%3A%2F%2Fgithub.com%2Fexample%2Fapps.git&selector=team%3Da", requestURI)
+	})
+
+	t.Run("list applications with fields", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{
+			Fields: applicationFields("status.health"),
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/applications?fields=metadata.resourceVersion%2Citems.metadata.name%2Citems.metadata.namespace%2Citems.status.health", requestURI)
+	})
+
+	t.Run("get application", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		app, err := cl.GetApplication(context.Background(), ApplicationRef{Name: "example"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "example", app.Name)
+	})
+
+	t.Run("get application in namespace", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		app, err := cl.GetApplication(context.Background(), ApplicationRef{Name: "example", Namespace: "team-a"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/applications/example?appNamespace=team-a", requestURI)
+		assert.Equal(t, "example", app.Name)
+	})
+
+	t.Run("refresh application", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		app, err := cl.RefreshApplication(context.Background(), ApplicationRef{Name: "example"}, argocdv3.RefreshTypeHard)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/applications/example?refresh=hard", requestURI)
+		assert.Equal(t, "example", app.Name)
+	})
+
+	t.Run("get managed resources", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		resources, err := cl.GetManagedResources(context.Background(), ApplicationRef{Name: "example"})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "Service", resources[0].Kind)
+	})
+
+	t.Run("list events", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		events, err := cl.ListEvents(context.Background(), ApplicationRef{Name: "example"}, ListEventsOptions{
+			ResourceName:      "example-0",
+			ResourceNamespace: "example-ns",
+			ResourceUID:       "3e4f5a6b",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/applications/example/events?resourceName=example-0&resourceNamespace=example-ns&resourceUID=3e4f5a6b", requestURI)
+		require.Len(t, events.Items, 1)
+		assert.Equal(t, "BackOff", events.Items[0].Reason)
+	})
+
+	t.Run("stream pod logs", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+		entries := []LogEntry{}
+
+		// when
+		err := cl.StreamPodLogs(context.Background(), ApplicationRef{Name: "example"}, PodLogsOptions{
+			PodName:   "example-0",
+			Namespace: "example-ns",
+			TailLines: 10,
+			Previous:  true,
+		}, func(e LogEntry) bool {
+			entries = append(entries, e)
+			return len(entries) < 2 // stop after the 2nd entry
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/applications/example/logs?follow=false&namespace=example-ns&podName=example-0&previous=true&tailLines=10", requestURI)
+		require.Len(t, entries, 2)
+		assert.Equal(t, LogEntry{
+			Content:      "2025-08-07 14:01:12.104 INFO  starting example server version=1.2.3",
+			PodName:      "example-0",
+			TimeStampStr: "2025-08-07T14:01:12.104938Z",
+		}, entries[0])
+	})
+
+	t.Run("stream pod logs with error", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		err := cl.StreamPodLogs(context.Background(), ApplicationRef{Name: "example-error"}, PodLogsOptions{
+			PodName: "unknown",
+		}, func(_ LogEntry) bool {
+			return true
+		})
+
+		// then
+		require.EqualError(t, err, `unexpected Argo CD status 404: pods "unknown" not found`)
+		assert.True(t, IsNotFound(err))
+	})
+
+	t.Run("escaped application name", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+		// when
+		_, err := cl.GetApplication(context.Background(), ApplicationRef{Name: "../projects"})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "/api/v1/applications/..%2Fprojects", requestURI)
+	})
+
+	t.Run("unexpected status", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("another-token"))
+
+		// when
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		apiErr := &APIError{}
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	})
+}
+
+func TestNewApplicationRef(t *testing.T) {
+
+	testdata := []struct {
+		name      string
+		namespace string
+		expected  ApplicationRef
+	}{
+		{
+			name:     "example",
+			expected: ApplicationRef{Name: "example"},
+		},
+		{
+			name:      "example",
+			namespace: "team-a",
+			expected:  ApplicationRef{Name: "example", Namespace: "team-a"},
+		},
+		{
+			name:     "team-a/example",
+			expected: ApplicationRef{Name: "example", Namespace: "team-a"},
+		},
+		{
+			name:      "team-a/example",
+			namespace: "team-a",
+			expected:  ApplicationRef{Name: "example", Namespace: "team-a"},
+		},
+	}
+
+	for _, td := range testdata {
+		t.Run(td.name+" in "+td.namespace, func(t *testing.T) {
+			// when
+			ref, err := NewApplicationRef(td.name, td.namespace)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, td.expected, ref)
+		})
+	}
+
+	t.Run("conflicting namespaces", func(t *testing.T) {
+		// when
+		_, err := NewApplicationRef("team-a/example", "team-b")
+
+		// then
+		require.EqualError(t, err, "the namespace of the application name 'team-a/example' does not match the application namespace 'team-b'")
+	})
+
+	t.Run("qualified name", func(t *testing.T) {
+		assert.Equal(t, "example", ApplicationRef{Name: "example"}.String())
+		assert.Equal(t, "team-a/example", ApplicationRef{Name: "example", Namespace: "team-a"}.String())
+	})
+}
+
+func TestClientRequestMethods(t *testing.T) {
+
+	// a minimal Argo CD server which echoes the method, content type and body of the request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secure-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid session: token is invalid","code":16,"message":"invalid session: token is invalid"}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Content-Type"), string(body))
+	}))
+	defer srv.Close()
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			// given
+			cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+			// when
+			resp, err := cl.do(context.Background(), method, "api/v1/applications/example", "application/json", strings.NewReader(`{"prune":true}`))
+
+			// then
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, method+` application/json {"prune":true}`, string(body))
+		})
+	}
+
+	t.Run("retry with the fresh token", func(t *testing.T) {
+		// given
+		modTime := time.Now().Add(-time.Minute)
+		path := writeTokenFile(t, filepath.Join(t.TempDir(), "token"), "stale-token", modTime)
+		creds, err := TokenFile(path)
+		require.NoError(t, err)
+		// the token is rotated without any change of the modification time and size of the file
+		writeTokenFile(t, path, "secure-token", modTime)
+		cl := newTestClient(t, srv.URL, creds)
+
+		// when
+		resp, err := cl.do(context.Background(), http.MethodPost, "api/v1/applications/example", "application/json", strings.NewReader(`{"prune":true}`))
+
+		// then
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		// the body was sent again
+		assert.Equal(t, `POST application/json {"prune":true}`, string(body))
+	})
+
+	t.Run("gRPC-gateway error", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, StaticToken("another-token"))
+
+		// when
+		err := cl.call(context.Background(), http.MethodPost, "api/v1/applications/example/sync", nil, map[string]any{"prune": true}, nil)
+
+		// then
+		apiErr := &APIError{}
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, &APIError{
+			StatusCode: http.StatusUnauthorized,
+			Code:       16,
+			Message:    "invalid session: token is invalid",
+		}, apiErr)
+		assert.True(t, IsUnauthorized(err))
+		assert.False(t, IsNotFound(err))
+	})
+}
+
+func newTestClient(t testing.TB, host string, creds Credentials) *client {
+	cl, err := NewClient(host, creds, ClientOptions{})
+	require.NoError(t, err)
+	return cl.(*client)
+}
@@ -0,0 +1,187 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context in which the responses of a cached client are not read from the cache,
+// but fetched from Argo CD (and then stored in the cache for the next requests)
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func cacheDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noCacheKey{}).(bool)
+	return disabled
+}
+
+// sharedFetchTimeout the timeout of a request shared by concurrent callers, which is not canceled when they give up
+const sharedFetchTimeout = 2 * time.Minute
+
+// NewCachedClient returns a client which keeps the responses of the given client in memory for the given TTL,
+// and which sends a single request to Argo CD when identical requests are received concurrently.
+// The pod logs are not cached, and the whole cache is purged when an application is refreshed, synced or rolled back.
+func NewCachedClient(cl Client, ttl time.Duration) Client {
+	return &cachedClient{
+		Client:       cl,
+		ttl:          ttl,
+		fetchTimeout: sharedFetchTimeout,
+		now:          time.Now,
+		entries:      map[string]cacheEntry{},
+	}
+}
+
+type cachedClient struct {
+	Client
+	ttl          time.Duration
+	fetchTimeout time.Duration
+	now          func() time.Time
+	group        singleflight.Group
+	mu           sync.Mutex
+	entries      map[string]cacheEntry
+	// generation is incremented when the cache is purged, so that the responses of the requests
+	// which were in flight at that time are neither stored nor shared with the callers which came after the purge
+	generation int
+}
+
+type cacheEntry struct {
+	value  any
+	expiry time.Time
+}
+
+var _ Client = &cachedClient{}
+
+func (c *cachedClient) ListApplications(ctx context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	return cached(ctx, c, fmt.Sprintf("applications:%+v", opts), func(ctx context.Context) (*argocdv3.ApplicationList, error) {
+		return c.Client.ListApplications(ctx, opts)
+	}, (*argocdv3.ApplicationList).DeepCopy)
+}
+
+func (c *cachedClient) GetApplication(ctx context.Context, app ApplicationRef) (*argocdv3.Application, error) {
+	return cached(ctx, c, fmt.Sprintf("application:%+v", app), func(ctx context.Context) (*argocdv3.Application, error) {
+		return c.Client.GetApplication(ctx, app)
+	}, (*argocdv3.Application).DeepCopy)
+}
+
+func (c *cachedClient) GetResourceTree(ctx context.Context, app ApplicationRef) (*argocdv3.ApplicationTree, error) {
+	return cached(ctx, c, fmt.Sprintf("resource-tree:%+v", app), func(ctx context.Context) (*argocdv3.ApplicationTree, error) {
+		return c.Client.GetResourceTree(ctx, app)
+	}, (*argocdv3.ApplicationTree).DeepCopy)
+}
+
+func (c *cachedClient) GetManagedResources(ctx context.Context, app ApplicationRef) ([]*argocdv3.ResourceDiff, error) {
+	return cached(ctx, c, fmt.Sprintf("managed-resources:%+v", app), func(ctx context.Context) ([]*argocdv3.ResourceDiff, error) {
+		return c.Client.GetManagedResources(ctx, app)
+	}, func(diffs []*argocdv3.ResourceDiff) []*argocdv3.ResourceDiff {
+		result := make([]*argocdv3.ResourceDiff, len(diffs))
+		for i, d := range diffs {
+			result[i] = d.DeepCopy()
+		}
+		return result
+	})
+}
+
+func (c *cachedClient) ListEvents(ctx context.Context, app ApplicationRef, opts ListEventsOptions) (*corev1.EventList, error) {
+	return cached(ctx, c, fmt.Sprintf("events:%+v:%+v", app, opts), func(ctx context.Context) (*corev1.EventList, error) {
+		return c.Client.ListEvents(ctx, app, opts)
+	}, (*corev1.EventList).DeepCopy)
+}
+
+func (c *cachedClient) ListProjects(ctx context.Context) (*argocdv3.AppProjectList, error) {
+	return cached(ctx, c, "projects", c.Client.ListProjects, (*argocdv3.AppProjectList).DeepCopy)
+}
+
+func (c *cachedClient) ListClusters(ctx context.Context) (*argocdv3.ClusterList, error) {
+	return cached(ctx, c, "clusters", c.Client.ListClusters, (*argocdv3.ClusterList).DeepCopy)
+}
+
+func (c *cachedClient) RefreshApplication(ctx context.Context, app ApplicationRef, refresh argocdv3.RefreshType) (*argocdv3.Application, error) {
+	defer c.purge()
+	return c.Client.RefreshApplication(ctx, app, refresh)
+}
+
+func (c *cachedClient) SyncApplication(ctx context.Context, app ApplicationRef, req SyncApplicationRequest) (*argocdv3.Application, error) {
+	defer c.purge()
+	return c.Client.SyncApplication(ctx, app, req)
+}
+
+func (c *cachedClient) RollbackApplication(ctx context.Context, app ApplicationRef, req RollbackApplicationRequest) (*argocdv3.Application, error) {
+	defer c.purge()
+	return c.Client.RollbackApplication(ctx, app, req)
+}
+
+func (c *cachedClient) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]cacheEntry{}
+	c.generation++
+}
+
+// cached returns a copy of the cached response with the given key if it has not expired (unless the cache is disabled in the context),
+// or else fetches the response, along with the concurrent callers with the same key which did not disable the cache.
+// The errors are not cached.
+func cached[T any](ctx context.Context, c *cachedClient, key string, fetch func(context.Context) (T, error), deepCopy func(T) T) (T, error) {
+	var zero T
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+	if found && !cacheDisabled(ctx) && c.now().Before(entry.expiry) {
+		return deepCopy(entry.value.(T)), nil
+	}
+	store := func(value T) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.generation != generation {
+			return
+		}
+		now := c.now()
+		for k, e := range c.entries {
+			if !now.Before(e.expiry) {
+				delete(c.entries, k)
+			}
+		}
+		c.entries[key] = cacheEntry{
+			value:  value,
+			expiry: now.Add(c.ttl),
+		}
+	}
+	if cacheDisabled(ctx) {
+		value, err := fetch(ctx)
+		if err != nil {
+			return zero, err
+		}
+		store(value)
+		return deepCopy(value), nil
+	}
+	// the request is shared with the other callers of the same generation, so it must neither be canceled when this caller gives up
+	// nor use the values of its context (eg: its wait notifier or its retry deadline), but it has its own timeout
+	result := c.group.DoChan(fmt.Sprintf("%d:%s", generation, key), func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.fetchTimeout)
+		defer cancel()
+		value, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		store(value)
+		return value, nil
+	})
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case r := <-result:
+		if r.Err != nil {
+			return zero, r.Err
+		}
+		return deepCopy(r.Val.(T)), nil
+	}
+}
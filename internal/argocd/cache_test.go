@@ -0,0 +1,267 @@
+package argocd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedClient(t *testing.T) {
+
+	newCachedClient := func() (*cachedClient, *countingArgoCDClient, *time.Time) {
+		now := time.Now()
+		counting := &countingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+		}
+		cl := NewCachedClient(counting, time.Minute).(*cachedClient)
+		cl.now = func() time.Time {
+			return now
+		}
+		return cl, counting, &now
+	}
+
+	t.Run("cached response", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		first, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+		first.Items[0].Name = "modified" // must not alter the cached response
+
+		// when
+		second, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), counting.calls.Load())
+		assert.NotEqual(t, "modified", second.Items[0].Name)
+	})
+
+	t.Run("different options", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{Name: "example"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), counting.calls.Load())
+	})
+
+	t.Run("expired response", func(t *testing.T) {
+		// given
+		cl, counting, now := newCachedClient()
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+		*now = now.Add(time.Minute)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), counting.calls.Load())
+	})
+
+	t.Run("without cache", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(WithoutCache(context.Background()), ListApplicationsOptions{})
+		require.NoError(t, err)
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+
+		// then
+		assert.Equal(t, int32(2), counting.calls.Load()) // the fresh response was cached for the last call
+	})
+
+	t.Run("purged after sync", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.SyncApplication(context.Background(), ApplicationRef{Name: "example"}, SyncApplicationRequest{})
+		require.NoError(t, err)
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+
+		// then
+		assert.Equal(t, int32(2), counting.calls.Load())
+	})
+
+	t.Run("errors not cached", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{Name: "example-error"})
+		require.Error(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{Name: "example-error"})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, int32(2), counting.calls.Load())
+	})
+
+	t.Run("concurrent requests coalesced", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		counting.wait = make(chan struct{})
+		ctx := &waitingContext{
+			Context: context.Background(),
+		}
+		wg := sync.WaitGroup{}
+		results := make([]*argocdv3.ApplicationList, 10)
+		errs := make([]error, 10)
+
+		// when
+		for i := range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = cl.ListApplications(ctx, ListApplicationsOptions{})
+			}()
+		}
+		// all the callers joined the request in flight, which is still blocked
+		require.Eventually(t, func() bool {
+			return ctx.waiting.Load() == 10
+		}, time.Second, time.Millisecond)
+		close(counting.wait)
+		wg.Wait()
+
+		// then
+		assert.Equal(t, int32(1), counting.calls.Load())
+		for i := range 10 {
+			require.NoError(t, errs[i])
+			assert.NotEmpty(t, results[i].Items)
+			assert.Equal(t, results[0], results[i])
+		}
+	})
+
+	t.Run("request in flight not shared after a purge", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		counting.wait = make(chan struct{})
+		errs := make(chan error, 2)
+		go func() {
+			_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+			errs <- err
+		}()
+		require.Eventually(t, func() bool {
+			return counting.calls.Load() == 1
+		}, time.Second, time.Millisecond)
+
+		// when
+		cl.purge()
+		go func() {
+			_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+			errs <- err
+		}()
+
+		// then
+		require.Eventually(t, func() bool {
+			return counting.calls.Load() == 2
+		}, time.Second, time.Millisecond)
+		close(counting.wait)
+		require.NoError(t, <-errs)
+		require.NoError(t, <-errs)
+	})
+
+	t.Run("shared request timeout", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		cl.fetchTimeout = 10 * time.Millisecond
+		counting.wait = make(chan struct{}) // never closed
+
+		// when
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("shared request without the values of the caller", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		ctx := WithWaitNotifier(context.Background(), func(_ context.Context, _ string) {})
+		ctx = WithRetryDeadline(ctx, time.Now().Add(time.Second))
+
+		// when
+		_, err := cl.ListApplications(ctx, ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		fetchCtx := *counting.ctx.Load()
+		assert.Nil(t, fetchCtx.Value(waitNotifierKey{}))
+		_, found := retryDeadline(fetchCtx)
+		assert.False(t, found)
+	})
+
+	t.Run("caller canceled", func(t *testing.T) {
+		// given
+		cl, counting, _ := newCachedClient()
+		counting.wait = make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// when
+		_, err := cl.ListApplications(ctx, ListApplicationsOptions{})
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+		// the request in flight completes, and its response is cached for the other callers
+		close(counting.wait)
+		require.Eventually(t, func() bool {
+			_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+			return err == nil
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, int32(1), counting.calls.Load())
+	})
+}
+
+// countingArgoCDClient counts the calls to `ListApplications` and records the context of the last one.
+// The calls are blocked until the `wait` channel (if any) is closed or their context is done
+type countingArgoCDClient struct {
+	Client
+	calls atomic.Int32
+	ctx   atomic.Pointer[context.Context]
+	wait  chan struct{}
+}
+
+func (c *countingArgoCDClient) ListApplications(ctx context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	c.calls.Add(1)
+	c.ctx.Store(&ctx)
+	if c.wait != nil {
+		select {
+		case <-c.wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return c.Client.ListApplications(ctx, opts)
+}
+
+// waitingContext counts the callers which wait for their context to be done, ie: for the response of the request in flight
+type waitingContext struct {
+	context.Context
+	waiting atomic.Int32
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.waiting.Add(1)
+	return c.Context.Done()
+}
@@ -0,0 +1,160 @@
+package argocd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ClientOptions the options of the connection to Argo CD
+type ClientOptions struct {
+	TLS TLSOptions
+	// BasePath the path under which Argo CD is served (eg: `/argocd` when the API server runs with `--rootpath=/argocd`),
+	// appended to the path of the URL of the instance (if any)
+	BasePath string
+	// Proxy the URL of the HTTP proxy to connect to Argo CD (defaults to the `HTTPS_PROXY` and `HTTP_PROXY` env vars).
+	// The hosts listed in the `NO_PROXY` env var are not proxied in either case.
+	Proxy string
+	// Timeout the timeout of each attempt of a request, including the reading of the response body (0 for no timeout)
+	Timeout time.Duration
+	Retry   RetryOptions
+	// RateLimit the limits of the requests sent to Argo CD (except the stream of application events)
+	RateLimit RateLimitOptions
+	// Logger the logger of the retries and of the requests delayed by the rate limit (no logs if nil)
+	Logger *slog.Logger
+}
+
+// TLSOptions the TLS settings of the connection to Argo CD
+type TLSOptions struct {
+	// Insecure skips the verification of the certificate of the Argo CD server
+	Insecure bool
+	// CAFile the path to the PEM-encoded CA bundle to verify the certificate of the Argo CD server,
+	// in addition to the system CAs
+	CAFile string
+	// ClientCertFile and ClientKeyFile the paths to the PEM-encoded client certificate and key, for mutual TLS
+	ClientCertFile string
+	ClientKeyFile  string
+	// MinVersion the minimum TLS version: `1.0`, `1.1`, `1.2` (default) or `1.3`
+	MinVersion string
+	// ServerName the name of the server to send with SNI and to verify the certificate against (instead of the host of the URL)
+	ServerName string
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newHTTPClient returns a new HTTP client with its own transport, so that the TLS settings
+// of an Argo CD instance neither apply to the other instances nor to the rest of the process
+func newHTTPClient(opts ClientOptions) (*http.Client, error) {
+	tlsConfig, err := opts.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := opts.proxy()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = tlsConfig
+	t.Proxy = proxy
+	return &http.Client{
+		Transport: t,
+		Timeout:   opts.Timeout,
+	}, nil
+}
+
+// proxy returns the func which selects the proxy of each request, from the `Proxy` option or else the env vars.
+// Unlike `http.ProxyFromEnvironment`, the env vars are read when the client is created rather than once per process.
+func (o ClientOptions) proxy() (func(*http.Request) (*url.URL, error), error) {
+	cfg := httpproxy.FromEnvironment()
+	if o.Proxy != "" {
+		u, err := url.Parse(o.Proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s': expected an absolute URL (eg: 'http://proxy.example.com:3128')", o.Proxy)
+		}
+		cfg.HTTPProxy = o.Proxy
+		cfg.HTTPSProxy = o.Proxy
+	}
+	proxyFunc := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// baseURL returns the URL of the Argo CD API server from the given URL of the instance and base path,
+// with a trailing `/` so that the paths of the API can be resolved against it
+func (o ClientOptions) baseURL(host string) (*url.URL, error) {
+	u, err := url.Parse(host)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Argo CD URL '%s': expected an absolute URL (eg: 'https://argocd.example.com')", host)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid Argo CD URL '%s': cannot have a query or a fragment", host)
+	}
+	u = u.JoinPath(o.BasePath)
+	if !strings.HasSuffix(u.Path, "/") {
+		u = u.JoinPath("/")
+	}
+	return u, nil
+}
+
+// resolve returns the URL of the given path (no heading `/`, with an optional query) relative to the given base URL
+func resolve(base *url.URL, path string) (string, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid request path '%s': %w", path, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (o TLSOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.Insecure, //nolint:gosec
+	}
+	if o.MinVersion != "" {
+		v, found := tlsVersions[o.MinVersion]
+		if !found {
+			return nil, fmt.Errorf("invalid TLS minimum version '%s': choose between '1.0', '1.1', '1.2' and '1.3'", o.MinVersion)
+		}
+		cfg.MinVersion = v
+	}
+	if o.CAFile != "" {
+		data, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no valid PEM-encoded certificate found in the CA file '%s'", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	switch {
+	case o.ClientCertFile != "" && o.ClientKeyFile != "":
+		cert, err := tls.LoadX509KeyPair(o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate and key: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	case o.ClientCertFile != "" || o.ClientKeyFile != "":
+		return nil, fmt.Errorf("the client certificate and key must be both set")
+	}
+	return cfg, nil
+}
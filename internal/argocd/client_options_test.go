@@ -0,0 +1,453 @@
+package argocd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTLS(t *testing.T) {
+
+	// a minimal Argo CD server with a certificate issued by a private CA (for `example.com` and `127.0.0.1`)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	defer srv.Close()
+	caFile := writePEM(t, "ca.crt", "CERTIFICATE", srv.Certificate().Raw)
+
+	t.Run("unknown CA", func(t *testing.T) {
+		// given
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.ErrorContains(t, err, "certificate signed by unknown authority")
+	})
+
+	t.Run("insecure", func(t *testing.T) {
+		// given
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			TLS: TLSOptions{
+				Insecure: true,
+			},
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("custom CA", func(t *testing.T) {
+		// given
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			TLS: TLSOptions{
+				CAFile: caFile,
+			},
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("server name", func(t *testing.T) {
+
+		t.Run("valid", func(t *testing.T) {
+			// given
+			cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+				TLS: TLSOptions{
+					CAFile:     caFile,
+					ServerName: "example.com",
+				},
+			})
+			require.NoError(t, err)
+
+			// when
+			_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+			// then
+			require.NoError(t, err)
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			// given
+			cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+				TLS: TLSOptions{
+					CAFile:     caFile,
+					ServerName: "argocd.internal",
+				},
+			})
+			require.NoError(t, err)
+
+			// when
+			_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+			// then
+			require.ErrorContains(t, err, "certificate is valid for example.com")
+		})
+	})
+
+	t.Run("min version", func(t *testing.T) {
+		// given
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}))
+		srv.TLS = &tls.Config{
+			MaxVersion: tls.VersionTLS12,
+		}
+		srv.StartTLS()
+		defer srv.Close()
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			TLS: TLSOptions{
+				Insecure:   true,
+				MinVersion: "1.3",
+			},
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.ErrorContains(t, err, "protocol version not supported")
+	})
+
+	t.Run("mutual TLS", func(t *testing.T) {
+		// given
+		certFile, keyFile, cert := newClientCertificate(t)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(cert)
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}))
+		srv.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+		srv.StartTLS()
+		defer srv.Close()
+		caFile := writePEM(t, "ca.crt", "CERTIFICATE", srv.Certificate().Raw)
+
+		t.Run("with client certificate", func(t *testing.T) {
+			// given
+			cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+				TLS: TLSOptions{
+					CAFile:         caFile,
+					ClientCertFile: certFile,
+					ClientKeyFile:  keyFile,
+				},
+			})
+			require.NoError(t, err)
+
+			// when
+			_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+			// then
+			require.NoError(t, err)
+		})
+
+		t.Run("without client certificate", func(t *testing.T) {
+			// given
+			cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+				TLS: TLSOptions{
+					CAFile: caFile,
+				},
+			})
+			require.NoError(t, err)
+
+			// when
+			_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+			// then
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		certFile, _, _ := newClientCertificate(t)
+
+		testdata := []struct {
+			name          string
+			opts          TLSOptions
+			expectedError string
+		}{
+			{
+				name: "invalid min version",
+				opts: TLSOptions{
+					MinVersion: "1.4",
+				},
+				expectedError: "invalid TLS minimum version '1.4': choose between '1.0', '1.1', '1.2' and '1.3'",
+			},
+			{
+				name: "missing CA file",
+				opts: TLSOptions{
+					CAFile: filepath.Join(t.TempDir(), "ca.crt"),
+				},
+				expectedError: "failed to read the CA file",
+			},
+			{
+				name: "invalid CA file",
+				opts: TLSOptions{
+					CAFile: writeFile(t, "ca.crt", "not a certificate"),
+				},
+				expectedError: "no valid PEM-encoded certificate found in the CA file",
+			},
+			{
+				name: "missing client key",
+				opts: TLSOptions{
+					ClientCertFile: certFile,
+				},
+				expectedError: "the client certificate and key must be both set",
+			},
+			{
+				name: "invalid client key",
+				opts: TLSOptions{
+					ClientCertFile: certFile,
+					ClientKeyFile:  writeFile(t, "tls.key", "not a key"),
+				},
+				expectedError: "failed to load the client certificate and key",
+			},
+		}
+		for _, td := range testdata {
+			t.Run(td.name, func(t *testing.T) {
+				// when
+				_, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+					TLS: td.opts,
+				})
+
+				// then
+				require.ErrorContains(t, err, td.expectedError)
+			})
+		}
+	})
+}
+
+// newClientCertificate generates a self-signed client certificate, and returns the paths to the certificate and key files
+func newClientCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "argocd-mcp",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return writePEM(t, "tls.crt", "CERTIFICATE", der), writePEM(t, "tls.key", "EC PRIVATE KEY", keyDER), cert
+}
+
+func writePEM(t *testing.T, name, blockType string, data []byte) string {
+	return writeFile(t, name, string(pem.EncodeToMemory(&pem.Block{
+		Type:  blockType,
+		Bytes: data,
+	})))
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0o600)
+	require.NoError(t, err)
+	return path
+}
+
+func TestTLSOptionsDefaultMinVersion(t *testing.T) {
+	// when
+	cfg, err := TLSOptions{}.config()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+}
+
+func TestClientURL(t *testing.T) {
+
+	// a minimal Argo CD server which records the paths of the requests
+	paths := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		_, _ = w.Write([]byte(`{"metadata":{"name":"example"}}`))
+	}))
+	defer srv.Close()
+
+	testdata := []struct {
+		name         string
+		host         string
+		basePath     string
+		expectedPath string
+	}{
+		{
+			name:         "no path",
+			host:         srv.URL,
+			expectedPath: "/api/v1/applications/my%2Fapp?appNamespace=team-a",
+		},
+		{
+			name:         "trailing slash",
+			host:         srv.URL + "/",
+			expectedPath: "/api/v1/applications/my%2Fapp?appNamespace=team-a",
+		},
+		{
+			name:         "base path",
+			host:         srv.URL,
+			basePath:     "/argocd/",
+			expectedPath: "/argocd/api/v1/applications/my%2Fapp?appNamespace=team-a",
+		},
+		{
+			name:         "path in URL and base path",
+			host:         srv.URL + "/gitops/",
+			basePath:     "argocd",
+			expectedPath: "/gitops/argocd/api/v1/applications/my%2Fapp?appNamespace=team-a",
+		},
+	}
+	for _, td := range testdata {
+		t.Run(td.name, func(t *testing.T) {
+			// given
+			paths = []string{}
+			cl, err := NewClient(td.host, StaticToken("secure-token"), ClientOptions{
+				BasePath: td.basePath,
+			})
+			require.NoError(t, err)
+
+			// when
+			_, err = cl.GetApplication(context.Background(), ApplicationRef{Name: "my/app", Namespace: "team-a"})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, []string{td.expectedPath}, paths)
+		})
+	}
+
+	t.Run("session login", func(t *testing.T) {
+		// given
+		paths = []string{}
+		cl, err := NewClient(srv.URL+"/", SessionLogin("admin", "secret"), ClientOptions{
+			BasePath: "argocd",
+		})
+		require.NoError(t, err)
+
+		// when
+		_, _ = cl.ListProjects(context.Background())
+
+		// then
+		require.NotEmpty(t, paths)
+		assert.Equal(t, "/argocd/api/v1/session", paths[0])
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		for _, host := range []string{"argocd.example.com", "https://", "https://argocd.example.com?foo=bar"} {
+			// when
+			_, err := NewClient(host, StaticToken("secure-token"), ClientOptions{})
+
+			// then
+			require.ErrorContains(t, err, "invalid Argo CD URL")
+		}
+	})
+}
+
+func TestClientProxy(t *testing.T) {
+
+	// a minimal forward proxy, which answers on behalf of the Argo CD server and records the proxied requests
+	newProxy := func(t *testing.T) (*httptest.Server, *[]string) {
+		requests := &[]string{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*requests = append(*requests, r.URL.String())
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}))
+		t.Cleanup(srv.Close)
+		return srv, requests
+	}
+
+	t.Run("explicit proxy", func(t *testing.T) {
+		// given
+		proxy, requests := newProxy(t)
+		cl, err := NewClient("http://argocd.example.com", StaticToken("secure-token"), ClientOptions{
+			Proxy: proxy.URL,
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListProjects(context.Background())
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://argocd.example.com/api/v1/projects"}, *requests)
+	})
+
+	t.Run("proxy from env", func(t *testing.T) {
+		// given
+		proxy, requests := newProxy(t)
+		t.Setenv("HTTP_PROXY", proxy.URL)
+		cl, err := NewClient("http://argocd.example.com", StaticToken("secure-token"), ClientOptions{})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListProjects(context.Background())
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://argocd.example.com/api/v1/projects"}, *requests)
+	})
+
+	t.Run("no proxy", func(t *testing.T) {
+		// given
+		proxy, requests := newProxy(t)
+		t.Setenv("NO_PROXY", ".example.com")
+		cl, err := NewClient("http://argocd.example.com", StaticToken("secure-token"), ClientOptions{
+			Proxy: proxy.URL,
+		})
+		require.NoError(t, err)
+		transport := cl.(*client).Transport.(*http.Transport)
+
+		// when
+		u, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "http://argocd.example.com/api/v1/projects", nil))
+
+		// then
+		require.NoError(t, err)
+		assert.Nil(t, u)
+		assert.Empty(t, *requests)
+	})
+
+	t.Run("invalid proxy", func(t *testing.T) {
+		// when
+		_, err := NewClient("http://argocd.example.com", StaticToken("secure-token"), ClientOptions{
+			Proxy: "proxy.example.com:3128",
+		})
+
+		// then
+		require.ErrorContains(t, err, "invalid proxy URL 'proxy.example.com:3128'")
+	})
+}
@@ -0,0 +1,241 @@
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials provides the bearer token to include in the requests to Argo CD
+type Credentials interface {
+	// Token returns the current token
+	Token(ctx context.Context) (string, error)
+	// Invalidate is called when Argo CD rejected the given token with a `401 Unauthorized` response.
+	// Returns `true` if a fresh token is available, in which case the request can be retried.
+	Invalidate(ctx context.Context, token string) bool
+}
+
+// StaticToken returns credentials with a token which never changes
+func StaticToken(token string) Credentials {
+	return staticToken(token)
+}
+
+type staticToken string
+
+func (t staticToken) Token(_ context.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t staticToken) Invalidate(_ context.Context, _ string) bool {
+	return false
+}
+
+// TokenFile returns credentials with the token read from the file at the given path.
+// The file is read again when its modification time or size changed (eg: when the token
+// is mounted from a Kubernetes Secret which was rotated), or when Argo CD rejected the token.
+// Returns an error if the file cannot be read or is empty.
+func TokenFile(path string) (Credentials, error) {
+	f := &tokenFile{
+		path: path,
+	}
+	if _, err := f.Token(context.Background()); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+type tokenFile struct {
+	path    string
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+func (f *tokenFile) Token(_ context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token file: %w", err)
+	}
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+	if err := f.read(info); err != nil {
+		return "", err
+	}
+	return f.token, nil
+}
+
+func (f *tokenFile) Invalidate(_ context.Context, token string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// the file may have changed without any change of its modification time and size
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false
+	}
+	if err := f.read(info); err != nil {
+		return false
+	}
+	return f.token != token
+}
+
+// read reads the token from the file, whose modification time and size are recorded
+// to detect the next changes (must be called with the lock held)
+func (f *tokenFile) read(info os.FileInfo) error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read the token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return fmt.Errorf("the token file '%s' is empty", f.path)
+	}
+	f.token = token
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return nil
+}
+
+// sessionRenewalMargin the delay before the expiry of the session token after which it is renewed
+const sessionRenewalMargin = time.Minute
+
+// SessionLogin returns credentials with a session token obtained by logging in to Argo CD as a local user
+// (via `POST /api/v1/session`). The token is renewed shortly before it expires, or when Argo CD rejected it.
+// The credentials must be used by a single client, which provides the URL and the HTTP client to log in.
+func SessionLogin(username, password string) Credentials {
+	return &sessionLogin{
+		username: username,
+		password: password,
+		now:      time.Now,
+	}
+}
+
+// binder is implemented by the credentials which send their own requests to Argo CD (eg: to log in),
+// with the URL and the HTTP client of the client which uses them
+type binder interface {
+	bind(base *url.URL, cl *http.Client)
+}
+
+var _ binder = &sessionLogin{}
+
+type sessionLogin struct {
+	username string
+	password string
+	base     *url.URL
+	client   *http.Client
+	now      func() time.Time
+	mu       sync.Mutex
+	token    string
+	expiry   time.Time
+}
+
+// bind sets the URL and the HTTP client to log in
+func (s *sessionLogin) bind(base *url.URL, cl *http.Client) {
+	s.base = base
+	s.client = cl
+}
+
+func (s *sessionLogin) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && (s.expiry.IsZero() || s.now().Before(s.expiry.Add(-sessionRenewalMargin))) {
+		return s.token, nil
+	}
+	if err := s.login(ctx); err != nil {
+		return "", err
+	}
+	return s.token, nil
+}
+
+func (s *sessionLogin) Invalidate(_ context.Context, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		// log in again on the next call to `Token`
+		s.token = ""
+	}
+	return true
+}
+
+// login obtains a new session token (must be called with the lock held)
+func (s *sessionLogin) login(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("the session login credentials are not used by any client")
+	}
+	data, err := json.Marshal(map[string]string{
+		"username": s.username,
+		"password": s.password,
+	})
+	if err != nil {
+		return err
+	}
+	u, err := resolve(s.base, "api/v1/session")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to log in to Argo CD as '%s': %w", s.username, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to log in to Argo CD as '%s': %w", s.username, newAPIError(resp.StatusCode, body))
+	}
+	session := struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.Unmarshal(body, &session); err != nil || session.Token == "" {
+		return fmt.Errorf("failed to log in to Argo CD as '%s': invalid response: %s", s.username, string(body))
+	}
+	expiry, err := jwtExpiry(session.Token)
+	if err != nil {
+		return fmt.Errorf("failed to log in to Argo CD as '%s': %w", s.username, err)
+	}
+	s.token = session.Token
+	s.expiry = expiry
+	return nil
+}
+
+// jwtExpiry returns the expiry (`exp` claim) of the given JWT, or a zero time if it has no expiry.
+// The signature of the token is not verified, since it is only used to know when to renew it.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid session token: not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid session token: %w", err)
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("invalid session token: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
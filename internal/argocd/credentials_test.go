@@ -0,0 +1,254 @@
+package argocd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticToken(t *testing.T) {
+	// given
+	creds := StaticToken("secure-token")
+
+	// when
+	token, err := creds.Token(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "secure-token", token)
+	assert.False(t, creds.Invalidate(context.Background(), token))
+}
+
+func TestTokenFile(t *testing.T) {
+
+	t.Run("read", func(t *testing.T) {
+		// given
+		path := writeTokenFile(t, filepath.Join(t.TempDir(), "token"), "secure-token\n", time.Now())
+		creds, err := TokenFile(path)
+		require.NoError(t, err)
+
+		// when
+		token, err := creds.Token(context.Background())
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "secure-token", token)
+	})
+
+	t.Run("reload when the file changed", func(t *testing.T) {
+		// given
+		path := writeTokenFile(t, filepath.Join(t.TempDir(), "token"), "old-token", time.Now().Add(-time.Minute))
+		creds, err := TokenFile(path)
+		require.NoError(t, err)
+		writeTokenFile(t, path, "new-token", time.Now())
+
+		// when
+		token, err := creds.Token(context.Background())
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "new-token", token)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		// given
+		modTime := time.Now().Add(-time.Minute)
+		path := writeTokenFile(t, filepath.Join(t.TempDir(), "token"), "old-token", modTime)
+		creds, err := TokenFile(path)
+		require.NoError(t, err)
+
+		t.Run("unchanged file", func(t *testing.T) {
+			// when
+			fresh := creds.Invalidate(context.Background(), "old-token")
+
+			// then
+			assert.False(t, fresh)
+		})
+
+		t.Run("changed file with the same modification time and size", func(t *testing.T) {
+			// given
+			writeTokenFile(t, path, "new-token", modTime)
+			token, err := creds.Token(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "old-token", token) // change not detected yet
+
+			// when
+			fresh := creds.Invalidate(context.Background(), "old-token")
+
+			// then
+			assert.True(t, fresh)
+			token, err = creds.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "new-token", token)
+		})
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		// when
+		_, err := TokenFile(filepath.Join(t.TempDir(), "token"))
+
+		// then
+		require.ErrorContains(t, err, "failed to read the token file")
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		// given
+		path := writeTokenFile(t, filepath.Join(t.TempDir(), "token"), " \n", time.Now())
+
+		// when
+		_, err := TokenFile(path)
+
+		// then
+		require.EqualError(t, err, "the token file '"+path+"' is empty")
+	})
+}
+
+func TestSessionLogin(t *testing.T) {
+
+	// a minimal Argo CD server which issues session tokens valid for 1h, and which only accepts the last one
+	var logins atomic.Int32
+	var lastToken atomic.Value
+	lastToken.Store("")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/session":
+			creds := map[string]string{}
+			if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds["username"] != "admin" || creds["password"] != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"Invalid username or password","code":16,"message":"Invalid username or password"}`))
+				return
+			}
+			n := logins.Add(1)
+			token := newJWT(t, fmt.Sprintf(`{"sub":"admin","jti":"%d","exp":%d}`, n, time.Now().Add(time.Hour).Unix()))
+			lastToken.Store(token)
+			_, _ = fmt.Fprintf(w, `{"token":"%s"}`, token)
+		case "/api/v1/applications":
+			if r.Header.Get("Authorization") != "Bearer "+lastToken.Load().(string) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("login once", func(t *testing.T) {
+		// given
+		logins.Store(0)
+		cl := newTestClient(t, srv.URL, SessionLogin("admin", "secret"))
+
+		// when
+		_, err1 := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		_, err2 := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		assert.Equal(t, int32(1), logins.Load())
+	})
+
+	t.Run("renew before expiry", func(t *testing.T) {
+		// given
+		logins.Store(0)
+		creds := SessionLogin("admin", "secret")
+		cl := newTestClient(t, srv.URL, creds)
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+		creds.(*sessionLogin).now = func() time.Time {
+			return time.Now().Add(time.Hour - 30*time.Second)
+		}
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), logins.Load())
+	})
+
+	t.Run("renew on 401", func(t *testing.T) {
+		// given
+		logins.Store(0)
+		cl := newTestClient(t, srv.URL, SessionLogin("admin", "secret"))
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+		lastToken.Store("revoked")
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), logins.Load())
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		// given
+		cl := newTestClient(t, srv.URL, SessionLogin("admin", "wrong"))
+
+		// when
+		_, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.ErrorContains(t, err, "failed to log in to Argo CD as 'admin'")
+		assert.True(t, IsUnauthorized(err))
+	})
+}
+
+func TestJWTExpiry(t *testing.T) {
+
+	t.Run("with expiry", func(t *testing.T) {
+		// when
+		expiry, err := jwtExpiry(newJWT(t, `{"exp":1760000000}`))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, time.Unix(1760000000, 0), expiry)
+	})
+
+	t.Run("without expiry", func(t *testing.T) {
+		// when
+		expiry, err := jwtExpiry(newJWT(t, `{"sub":"admin"}`))
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, expiry.IsZero())
+	})
+
+	t.Run("not a JWT", func(t *testing.T) {
+		// when
+		_, err := jwtExpiry("secure-token")
+
+		// then
+		require.EqualError(t, err, "invalid session token: not a JWT")
+	})
+}
+
+// newJWT returns an (unsigned) JWT with the given claims
+func newJWT(t *testing.T, claims string) string {
+	require.True(t, json.Valid([]byte(claims)))
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+}
+
+// writeTokenFile writes the token in the file at the given path, with the given modification time
+func writeTokenFile(t *testing.T, path, token string, modTime time.Time) string {
+	err := os.WriteFile(path, []byte(token), 0o600)
+	require.NoError(t, err)
+	err = os.Chtimes(path, modTime, modTime)
+	require.NoError(t, err)
+	return path
+}
@@ -2,34 +2,353 @@ package argocd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
 	"strings"
 
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+
 	testresources "github.com/codeready-toolchain/argocd-mcp/test/resources"
 )
 
 type FakeArgoCDClient struct {
+	// SyncRequests the sync requests received by the fake client, indexed by (qualified) application name
+	SyncRequests map[string]SyncApplicationRequest
+	// Refreshes the type of refreshes received by the fake client, indexed by (qualified) application name
+	Refreshes map[string]argocdv3.RefreshType
+	// RollbackRequests the rollback requests received by the fake client, indexed by (qualified) application name
+	RollbackRequests map[string]RollbackApplicationRequest
+	// PodLogsRequests the options of the pod logs requests received by the fake client, indexed by (qualified) application name
+	PodLogsRequests map[string]PodLogsOptions
+	// OperationsNotStarted if set, the requested operations are never started
+	// (by default, they are started and completed right away)
+	OperationsNotStarted bool
+	// operations the state of the operations started by the fake client, indexed by (qualified) application name
+	operations map[string]*argocdv3.OperationState
+}
+
+var _ Client = &FakeArgoCDClient{}
+
+func (c *FakeArgoCDClient) ListApplications(_ context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	var data string
+	switch opts.Name {
+	case "example", "example-duplicate":
+		data = testresources.ExampleApplicationStr
+	case "example-error":
+		return nil, &APIError{
+			StatusCode: http.StatusInternalServerError,
+		}
+	default:
+		// the applications are filtered on their name below
+		data = testresources.ApplicationsStr
+	}
+	apps, err := unmarshalApplicationList(data)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Name == "example-duplicate" {
+		// the same application in 2 namespaces
+		apps.Items = append(apps.Items, *apps.Items[0].DeepCopy())
+		for i, ns := range []string{"team-a", "team-b"} {
+			apps.Items[i].Name = opts.Name
+			apps.Items[i].Namespace = ns
+		}
+	}
+	return filterApplicationList(apps, opts)
+}
+
+func (c *FakeArgoCDClient) GetApplication(ctx context.Context, ref ApplicationRef) (*argocdv3.Application, error) {
+	apps, err := c.ListApplications(ctx, ListApplicationsOptions{
+		Name:         ref.Name,
+		AppNamespace: ref.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(apps.Items) == 0 {
+		return nil, &APIError{
+			StatusCode: http.StatusNotFound,
+			Code:       5,
+			Message:    fmt.Sprintf("applications.argoproj.io \"%s\" not found", ref.Name),
+		}
+	}
+	app := &apps.Items[0]
+	if s, found := c.operations[qualifiedName(*app)]; found {
+		app.Status.OperationState = s.DeepCopy()
+	}
+	return app, nil
+}
+
+func (c *FakeArgoCDClient) RefreshApplication(ctx context.Context, ref ApplicationRef, refresh argocdv3.RefreshType) (*argocdv3.Application, error) {
+	app, err := c.GetApplication(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if c.Refreshes == nil {
+		c.Refreshes = map[string]argocdv3.RefreshType{}
+	}
+	c.Refreshes[ref.String()] = refresh
+	return app, nil
+}
+
+func (c *FakeArgoCDClient) GetResourceTree(_ context.Context, ref ApplicationRef) (*argocdv3.ApplicationTree, error) {
+	switch ref.Name {
+	case "example":
+		tree := &argocdv3.ApplicationTree{}
+		if err := json.Unmarshal([]byte(testresources.ExampleResourceTreeStr), tree); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	case "example-error":
+		return nil, &APIError{
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return nil, fmt.Errorf("not implemented: get resource tree of application '%s'", ref)
+}
+
+func (c *FakeArgoCDClient) GetManagedResources(_ context.Context, ref ApplicationRef) ([]*argocdv3.ResourceDiff, error) {
+	switch ref.Name {
+	case "example":
+		resources := &struct {
+			Items []*argocdv3.ResourceDiff `json:"items"`
+		}{}
+		if err := json.Unmarshal([]byte(testresources.ExampleManagedResourcesStr), resources); err != nil {
+			return nil, err
+		}
+		return resources.Items, nil
+	case "example-error":
+		return nil, &APIError{
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return nil, fmt.Errorf("not implemented: get managed resources of application '%s'", ref)
+}
+
+func (c *FakeArgoCDClient) ListEvents(_ context.Context, ref ApplicationRef, opts ListEventsOptions) (*corev1.EventList, error) {
+	switch ref.Name {
+	case "example":
+		events := &corev1.EventList{}
+		if err := json.Unmarshal([]byte(testresources.ExampleEventsStr), events); err != nil {
+			return nil, err
+		}
+		// filter the events on the involved object, like Argo CD does
+		items := []corev1.Event{}
+		for _, e := range events.Items {
+			if (opts.ResourceName == "" || e.InvolvedObject.Name == opts.ResourceName) &&
+				(opts.ResourceNamespace == "" || e.InvolvedObject.Namespace == opts.ResourceNamespace) &&
+				(opts.ResourceUID == "" || string(e.InvolvedObject.UID) == opts.ResourceUID) {
+				items = append(items, e)
+			}
+		}
+		events.Items = items
+		return events, nil
+	case "example-error":
+		return nil, &APIError{
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return nil, fmt.Errorf("not implemented: list events of application '%s'", ref)
 }
 
-func (c *FakeArgoCDClient) GetWithContext(_ context.Context, path string) (*http.Response, error) {
-	switch path {
-	case "api/v1/applications":
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(strings.NewReader(testresources.ApplicationsStr)),
-		}, nil
-	case "api/v1/applications?name=example":
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(strings.NewReader(testresources.ExampleApplicationStr)),
-		}, nil
-	case "api/v1/applications?name=example-error":
-		return &http.Response{
+func (c *FakeArgoCDClient) StreamPodLogs(_ context.Context, ref ApplicationRef, opts PodLogsOptions, handle func(LogEntry) bool) error {
+	switch ref.Name {
+	case "example":
+		if c.PodLogsRequests == nil {
+			c.PodLogsRequests = map[string]PodLogsOptions{}
+		}
+		c.PodLogsRequests[ref.String()] = opts
+		if opts.PodName != "example-0" && (opts.Kind != "StatefulSet" || opts.ResourceName != "example") {
+			return &APIError{
+				StatusCode: http.StatusNotFound,
+				Code:       5,
+				Message:    "no pods found",
+			}
+		}
+		// filter the entries on their content, like Argo CD does
+		return decodeStreamResults(strings.NewReader(testresources.ExamplePodLogsStr), "log entry", func(e LogEntry) bool {
+			if e.Last {
+				return false
+			}
+			if opts.Filter != "" && !strings.Contains(e.Content, opts.Filter) {
+				return true
+			}
+			return handle(e)
+		})
+	case "example-error":
+		return &APIError{
 			StatusCode: http.StatusInternalServerError,
-			Body:       io.NopCloser(strings.NewReader("")),
-		}, nil
+		}
+	}
+	return fmt.Errorf("not implemented: stream pod logs of application '%s'", ref)
+}
+
+// startOperation starts and completes the operation requested on the given application, which is synced with the selected resources
+// (or all its resources), unless the operations are not started
+func (c *FakeArgoCDClient) startOperation(app *argocdv3.Application) {
+	if c.OperationsNotStarted {
+		return
+	}
+	now := metav1.Now()
+	op := app.Operation.DeepCopy()
+	revision := op.Sync.Revision
+	if revision == "" {
+		revision = app.Status.Sync.Revision
+	}
+	s := &argocdv3.OperationState{
+		Operation:  *op,
+		Phase:      synccommon.OperationSucceeded,
+		Message:    "successfully synced (all tasks run)",
+		StartedAt:  now,
+		FinishedAt: &now,
+		SyncResult: &argocdv3.SyncOperationResult{
+			Revision: revision,
+		},
+	}
+	for _, r := range app.Status.Resources {
+		if len(op.Sync.Resources) > 0 && !slices.ContainsFunc(op.Sync.Resources, func(s argocdv3.SyncOperationResource) bool {
+			return s.Group == r.Group && s.Kind == r.Kind && s.Namespace == r.Namespace && s.Name == r.Name
+		}) {
+			continue
+		}
+		message := fmt.Sprintf("%s/%s configured", strings.ToLower(r.Kind), r.Name)
+		if op.Sync.DryRun {
+			message += " (dry run)"
+		}
+		s.SyncResult.Resources = append(s.SyncResult.Resources, &argocdv3.ResourceResult{
+			Group:     r.Group,
+			Version:   r.Version,
+			Kind:      r.Kind,
+			Namespace: r.Namespace,
+			Name:      r.Name,
+			Status:    synccommon.ResultCodeSynced,
+			Message:   message,
+			SyncPhase: synccommon.SyncPhaseSync,
+		})
+	}
+	if c.operations == nil {
+		c.operations = map[string]*argocdv3.OperationState{}
+	}
+	c.operations[qualifiedName(*app)] = s
+}
+
+// WatchApplications sends all the applications as `ADDED` events if the resource version is empty,
+// and then closes the stream
+func (c *FakeArgoCDClient) WatchApplications(_ context.Context, resourceVersion string, handle func(argocdv3.ApplicationWatchEvent) bool) error {
+	if resourceVersion != "" {
+		return nil
+	}
+	apps, err := unmarshalApplicationList(testresources.ApplicationsStr)
+	if err != nil {
+		return err
+	}
+	for _, app := range apps.Items {
+		if !handle(argocdv3.ApplicationWatchEvent{
+			Type:        watch.Added,
+			Application: app,
+		}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *FakeArgoCDClient) ListProjects(_ context.Context) (*argocdv3.AppProjectList, error) {
+	return nil, fmt.Errorf("not implemented: list projects")
+}
+
+func (c *FakeArgoCDClient) ListClusters(_ context.Context) (*argocdv3.ClusterList, error) {
+	return nil, fmt.Errorf("not implemented: list clusters")
+}
+
+func (c *FakeArgoCDClient) SyncApplication(ctx context.Context, ref ApplicationRef, req SyncApplicationRequest) (*argocdv3.Application, error) {
+	app, err := c.GetApplication(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if c.SyncRequests == nil {
+		c.SyncRequests = map[string]SyncApplicationRequest{}
+	}
+	c.SyncRequests[ref.String()] = req
+	// the operation is requested, the operation state is still the one of the previous operation
+	app.Operation = &argocdv3.Operation{
+		Sync: &argocdv3.SyncOperation{
+			Revision:  req.Revision,
+			DryRun:    req.DryRun,
+			Prune:     req.Prune,
+			Resources: req.Resources,
+		},
+	}
+	c.startOperation(app)
+	return app, nil
+}
+
+func (c *FakeArgoCDClient) RollbackApplication(ctx context.Context, ref ApplicationRef, req RollbackApplicationRequest) (*argocdv3.Application, error) {
+	app, err := c.GetApplication(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	var revision string
+	for _, h := range app.Status.History {
+		if h.ID == req.ID {
+			revision = h.Revision
+		}
+	}
+	if revision == "" {
+		return nil, &APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       3,
+			Message:    fmt.Sprintf("application '%s' does not have deployment id '%d' in history", ref.Name, req.ID),
+		}
+	}
+	if c.RollbackRequests == nil {
+		c.RollbackRequests = map[string]RollbackApplicationRequest{}
+	}
+	c.RollbackRequests[ref.String()] = req
+	// the operation is requested, the operation state is still the one of the previous operation
+	app.Operation = &argocdv3.Operation{
+		Sync: &argocdv3.SyncOperation{
+			Revision: revision,
+			DryRun:   req.DryRun,
+			Prune:    req.Prune,
+		},
+	}
+	c.startOperation(app)
+	return app, nil
+}
+
+func unmarshalApplicationList(data string) (*argocdv3.ApplicationList, error) {
+	apps := &argocdv3.ApplicationList{}
+	if err := json.Unmarshal([]byte(data), apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// filterApplicationList filters the applications like Argo CD does with the `projects`,
+// `selector`, `repo` and `appNamespace` query params
+func filterApplicationList(apps *argocdv3.ApplicationList, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	selector, err := labels.Parse(opts.Selector)
+	if err != nil {
+		return nil, &APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       3,
+			Message:    fmt.Sprintf("error parsing the selector: %s", err.Error()),
+		}
+	}
+	items := []argocdv3.Application{}
+	for _, app := range apps.Items {
+		if opts.matches(&app, selector) {
+			items = append(items, app)
+		}
 	}
-	return nil, fmt.Errorf("not implemented: %s", path)
+	apps.Items = items
+	return apps, nil
 }
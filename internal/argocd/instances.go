@@ -0,0 +1,152 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Instance a named Argo CD instance
+type Instance struct {
+	Name   string
+	URL    string
+	Client Client
+	// KubernetesAPI whether the Applications are read through the Kubernetes API instead of the Argo CD API server
+	KubernetesAPI bool
+}
+
+// Instances the registry of the Argo CD instances that the server can query.
+// The first instance is the default one, used when a tool call does not specify any instance.
+type Instances struct {
+	instances []Instance
+}
+
+// NewInstances returns a new registry with the given instances, whose names must be unique and not empty
+func NewInstances(instances ...Instance) (*Instances, error) {
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("at least one Argo CD instance must be configured")
+	}
+	names := map[string]bool{}
+	for _, i := range instances {
+		if i.Name == "" {
+			return nil, fmt.Errorf("the name of the Argo CD instance with URL '%s' is missing", i.URL)
+		}
+		if names[i.Name] {
+			return nil, fmt.Errorf("duplicate Argo CD instance name: '%s'", i.Name)
+		}
+		names[i.Name] = true
+	}
+	return &Instances{
+		instances: instances,
+	}, nil
+}
+
+// Get returns the instance with the given name, or the default instance if the name is empty
+func (r *Instances) Get(name string) (Instance, error) {
+	if name == "" {
+		return r.instances[0], nil
+	}
+	for _, i := range r.instances {
+		if i.Name == name {
+			return i, nil
+		}
+	}
+	return Instance{}, fmt.Errorf("unknown Argo CD instance '%s', choose between '%s'", name, strings.Join(r.Names(), "', '"))
+}
+
+// Client returns the client of the instance with the given name, or of the default instance if the name is empty
+func (r *Instances) Client(name string) (Client, error) {
+	i, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return i.Client, nil
+}
+
+// All returns all the instances, starting with the default one
+func (r *Instances) All() []Instance {
+	return r.instances
+}
+
+// HasAPIServer returns true if at least one instance is queried through the Argo CD API server
+// (ie: if the tools which need the Argo CD API server are supported by at least one instance)
+func (r *Instances) HasAPIServer() bool {
+	for _, i := range r.instances {
+		if !i.KubernetesAPI {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names of all the instances, starting with the default one
+func (r *Instances) Names() []string {
+	names := make([]string, 0, len(r.instances))
+	for _, i := range r.instances {
+		names = append(names, i.Name)
+	}
+	return names
+}
+
+var ListInstancesTool = &mcp.Tool{
+	Name:         "listInstances",
+	Description:  "list the Argo CD instances that can be queried, using their name in the `instance` argument of the other tools",
+	InputSchema:  ListInstancesInputSchema,
+	OutputSchema: ListInstancesOutputSchema,
+}
+
+type ListInstancesInput struct{}
+
+var ListInstancesInputSchema, _ = jsonschema.For[ListInstancesInput](&jsonschema.ForOptions{})
+
+type ListInstancesOutput InstanceList
+
+var ListInstancesOutputSchema, _ = jsonschema.For[ListInstancesOutput](&jsonschema.ForOptions{})
+
+func ListInstancesToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[ListInstancesInput, ListInstancesOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, _ ListInstancesInput) (*mcp.CallToolResult, ListInstancesOutput, error) {
+		return nil, ListInstancesOutput(listInstances(ctx, logger, instances)), nil
+	}
+}
+
+type InstanceList struct {
+	Instances []InstanceSummary `json:"instances"`
+}
+
+// InstanceSummary the name and URL of an Argo CD instance
+type InstanceSummary struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Default whether this instance is used when a tool call does not specify any instance
+	Default bool `json:"default,omitempty"`
+	// KubernetesAPI whether the Applications of this instance are read through the Kubernetes API, in which case the tools
+	// which need the Argo CD API server (eg: `resourceDiff`, `podLogs`, `syncApplication`) are not supported
+	KubernetesAPI bool `json:"kubernetesAPI,omitempty"`
+}
+
+func listInstances(ctx context.Context, logger *slog.Logger, instances *Instances) InstanceList {
+	result := InstanceList{
+		Instances: make([]InstanceSummary, 0, len(instances.All())),
+	}
+	for i, instance := range instances.All() {
+		result.Instances = append(result.Instances, InstanceSummary{
+			Name:          instance.Name,
+			URL:           instance.URL,
+			Default:       i == 0,
+			KubernetesAPI: instance.KubernetesAPI,
+		})
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		resultStr, err := json.Marshal(result)
+		if err != nil {
+			logger.Error("failed to convert instances to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "listInstances", "result", string(resultStr))
+	}
+	return result
+}
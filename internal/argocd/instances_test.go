@@ -0,0 +1,161 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInstances(t *testing.T) {
+
+	t.Run("ok", func(t *testing.T) {
+		// when
+		instances, err := NewInstances(
+			Instance{Name: "dev", URL: "https://argocd.dev"},
+			Instance{Name: "prod", URL: "https://argocd.prod"},
+		)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dev", "prod"}, instances.Names())
+	})
+
+	testdata := []struct {
+		name          string
+		instances     []Instance
+		expectedError string
+	}{
+		{
+			name:          "no instance",
+			expectedError: "at least one Argo CD instance must be configured",
+		},
+		{
+			name: "missing name",
+			instances: []Instance{
+				{URL: "https://argocd.dev"},
+			},
+			expectedError: "the name of the Argo CD instance with URL 'https://argocd.dev' is missing",
+		},
+		{
+			name: "duplicate name",
+			instances: []Instance{
+				{Name: "dev", URL: "https://argocd.dev"},
+				{Name: "dev", URL: "https://argocd.prod"},
+			},
+			expectedError: "duplicate Argo CD instance name: 'dev'",
+		},
+	}
+	for _, td := range testdata {
+		t.Run(td.name, func(t *testing.T) {
+			// when
+			_, err := NewInstances(td.instances...)
+
+			// then
+			require.EqualError(t, err, td.expectedError)
+		})
+	}
+}
+
+func TestGetInstance(t *testing.T) {
+
+	// given
+	instances, err := NewInstances(
+		Instance{Name: "dev", URL: "https://argocd.dev"},
+		Instance{Name: "prod", URL: "https://argocd.prod"},
+	)
+	require.NoError(t, err)
+
+	t.Run("default", func(t *testing.T) {
+		// when
+		i, err := instances.Get("")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "dev", i.Name)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		// when
+		i, err := instances.Get("prod")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "prod", i.Name)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		// when
+		_, err := instances.Get("staging")
+
+		// then
+		require.EqualError(t, err, "unknown Argo CD instance 'staging', choose between 'dev', 'prod'")
+	})
+}
+
+func TestListInstances(t *testing.T) {
+	// given
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+	instances, err := NewInstances(
+		Instance{Name: "dev", URL: "https://argocd.dev"},
+		Instance{Name: "prod", URL: "https://api.prod:6443/apis/argoproj.io/v1alpha1/namespaces/argocd/applications", KubernetesAPI: true},
+	)
+	require.NoError(t, err)
+
+	// when
+	result := listInstances(context.Background(), logger, instances)
+
+	// then
+	assert.Equal(t, InstanceList{
+		Instances: []InstanceSummary{
+			{
+				Name:    "dev",
+				URL:     "https://argocd.dev",
+				Default: true,
+			},
+			{
+				Name:          "prod",
+				URL:           "https://api.prod:6443/apis/argoproj.io/v1alpha1/namespaces/argocd/applications",
+				KubernetesAPI: true,
+			},
+		},
+	}, result)
+}
+
+func TestHasAPIServer(t *testing.T) {
+
+	t.Run("some instances with the Argo CD API server", func(t *testing.T) {
+		// given
+		instances, err := NewInstances(
+			Instance{Name: "dev", URL: "https://argocd.dev"},
+			Instance{Name: "prod", KubernetesAPI: true},
+		)
+		require.NoError(t, err)
+
+		// when
+		result := instances.HasAPIServer()
+
+		// then
+		assert.True(t, result)
+	})
+
+	t.Run("all instances through the Kubernetes API", func(t *testing.T) {
+		// given
+		instances, err := NewInstances(
+			Instance{Name: "dev", KubernetesAPI: true},
+			Instance{Name: "prod", KubernetesAPI: true},
+		)
+		require.NoError(t, err)
+
+		// when
+		result := instances.HasAPIServer()
+
+		// then
+		assert.False(t, result)
+	})
+}
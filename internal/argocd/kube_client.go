@@ -0,0 +1,301 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	applicationsGVR = argocdv3.SchemeGroupVersion.WithResource("applications")
+	appProjectsGVR  = argocdv3.SchemeGroupVersion.WithResource("appprojects")
+	eventsGVR       = corev1.SchemeGroupVersion.WithResource("events")
+)
+
+// KubeOptions the options of the connection to the Kubernetes API server of the cluster in which Argo CD runs
+type KubeOptions struct {
+	// Kubeconfig the path to the kubeconfig file (optional, defaults to the `KUBECONFIG` env var, `~/.kube/config`,
+	// or the in-cluster configuration when running in a pod)
+	Kubeconfig string
+	// Context the kubeconfig context to use (optional, defaults to the current context)
+	Context string
+	// Timeout the timeout of each request (0 for no timeout)
+	Timeout time.Duration
+}
+
+// NewKubeConfig returns the configuration to connect to the Kubernetes API server with the given options
+func NewKubeConfig(opts KubeOptions) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if opts.Kubeconfig != "" {
+		rules.ExplicitPath = opts.Kubeconfig
+	}
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{
+		CurrentContext: opts.Context,
+	}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the Kubernetes configuration: %w", err)
+	}
+	cfg.Timeout = opts.Timeout
+	return cfg, nil
+}
+
+// NewKubeClient returns a client which reads the Application and AppProject resources in the given namespace
+// (ie: the namespace of the Argo CD control plane) through the Kubernetes API, instead of the Argo CD API server.
+// The operations which need the Argo CD API server (eg: managed resources, pod logs, sync, rollback, etc.) are not supported,
+// and the resource tree only contains the resources listed in the status of the applications.
+func NewKubeClient(cl dynamic.Interface, namespace string) Client {
+	return &kubeClient{
+		client:    cl,
+		namespace: namespace,
+	}
+}
+
+type kubeClient struct {
+	client    dynamic.Interface
+	namespace string
+}
+
+var _ Client = &kubeClient{}
+
+// errNotSupported the error returned by the operations which need the Argo CD API server
+var errNotSupported = errors.New("not supported when reading the Applications through the Kubernetes API")
+
+func notSupported(operation string) error {
+	return fmt.Errorf("%s: %w", operation, errNotSupported)
+}
+
+// ListApplications lists the applications in the namespace of the options, or else in the namespace of the client.
+// The name and label selector are applied by the Kubernetes API server, the other options on the client side.
+func (c *kubeClient) ListApplications(ctx context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	selector, err := labels.Parse(opts.Selector)
+	if err != nil {
+		return nil, &APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("error parsing the selector: %s", err.Error()),
+		}
+	}
+	listOpts := metav1.ListOptions{
+		LabelSelector: opts.Selector,
+	}
+	if opts.Name != "" {
+		listOpts.FieldSelector = fields.OneTermEqualSelector("metadata.name", opts.Name).String()
+	}
+	list, err := c.client.Resource(applicationsGVR).Namespace(c.namespaceOr(opts.AppNamespace)).List(ctx, listOpts)
+	if err != nil {
+		return nil, newKubeAPIError(err)
+	}
+	apps := &argocdv3.ApplicationList{
+		Items: make([]argocdv3.Application, 0, len(list.Items)),
+	}
+	apps.ResourceVersion = list.GetResourceVersion()
+	for _, item := range list.Items {
+		app := argocdv3.Application{}
+		if err := fromUnstructured(&item, &app); err != nil {
+			return nil, err
+		}
+		if opts.matches(&app, selector) {
+			apps.Items = append(apps.Items, app)
+		}
+	}
+	return apps, nil
+}
+
+func (c *kubeClient) GetApplication(ctx context.Context, ref ApplicationRef) (*argocdv3.Application, error) {
+	obj, err := c.client.Resource(applicationsGVR).Namespace(c.namespaceOr(ref.Namespace)).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, newKubeAPIError(err)
+	}
+	app := &argocdv3.Application{}
+	if err := fromUnstructured(obj, app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// ListEvents lists the events of the application itself. The events of its resources are not supported,
+// since the resources may be deployed on another cluster.
+func (c *kubeClient) ListEvents(ctx context.Context, ref ApplicationRef, opts ListEventsOptions) (*corev1.EventList, error) {
+	if opts != (ListEventsOptions{}) {
+		return nil, notSupported("list the events of the resources of an application")
+	}
+	namespace := c.namespaceOr(ref.Namespace)
+	list, err := c.client.Resource(eventsGVR).Namespace(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.Set{
+			"involvedObject.kind": "Application",
+			"involvedObject.name": ref.Name,
+		}.String(),
+	})
+	if err != nil {
+		return nil, newKubeAPIError(err)
+	}
+	events := &corev1.EventList{
+		Items: make([]corev1.Event, 0, len(list.Items)),
+	}
+	for _, item := range list.Items {
+		e := corev1.Event{}
+		if err := fromUnstructured(&item, &e); err != nil {
+			return nil, err
+		}
+		// in case the field selector was not applied by the server
+		if e.InvolvedObject.Kind == "Application" && e.InvolvedObject.Name == ref.Name {
+			events.Items = append(events.Items, e)
+		}
+	}
+	return events, nil
+}
+
+func (c *kubeClient) ListProjects(ctx context.Context) (*argocdv3.AppProjectList, error) {
+	list, err := c.client.Resource(appProjectsGVR).Namespace(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, newKubeAPIError(err)
+	}
+	projects := &argocdv3.AppProjectList{
+		Items: make([]argocdv3.AppProject, 0, len(list.Items)),
+	}
+	for _, item := range list.Items {
+		p := argocdv3.AppProject{}
+		if err := fromUnstructured(&item, &p); err != nil {
+			return nil, err
+		}
+		projects.Items = append(projects.Items, p)
+	}
+	return projects, nil
+}
+
+// WatchApplications watches the applications in the namespace of the client, after the given resource version
+// (or from the current state, as `ADDED` events, if the resource version is empty)
+func (c *kubeClient) WatchApplications(ctx context.Context, resourceVersion string, handle func(argocdv3.ApplicationWatchEvent) bool) error {
+	w, err := c.client.Resource(applicationsGVR).Namespace(c.namespace).Watch(ctx, metav1.ListOptions{
+		ResourceVersion: resourceVersion,
+	})
+	if err != nil {
+		return newKubeAPIError(err)
+	}
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return nil
+			}
+			switch e.Type {
+			case watch.Added, watch.Modified, watch.Deleted:
+				obj, ok := e.Object.(*unstructured.Unstructured)
+				if !ok {
+					return fmt.Errorf("unexpected object in application event: %T", e.Object)
+				}
+				app := argocdv3.Application{}
+				if err := fromUnstructured(obj, &app); err != nil {
+					return err
+				}
+				if !handle(argocdv3.ApplicationWatchEvent{
+					Type:        e.Type,
+					Application: app,
+				}) {
+					return nil
+				}
+			case watch.Error:
+				// eg: `410 Gone` when the resource version is too old
+				return newKubeAPIError(apierrors.FromObject(e.Object))
+			}
+		}
+	}
+}
+
+func (c *kubeClient) RefreshApplication(_ context.Context, _ ApplicationRef, _ argocdv3.RefreshType) (*argocdv3.Application, error) {
+	return nil, notSupported("refresh an application")
+}
+
+// GetResourceTree returns the resources listed in the status of the application, ie: the resources managed by the application,
+// without their parents and children (eg: the pods of a deployment), which are only known by the Argo CD API server
+func (c *kubeClient) GetResourceTree(ctx context.Context, ref ApplicationRef) (*argocdv3.ApplicationTree, error) {
+	app, err := c.GetApplication(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	tree := &argocdv3.ApplicationTree{
+		Nodes: make([]argocdv3.ResourceNode, 0, len(app.Status.Resources)),
+	}
+	for _, r := range app.Status.Resources {
+		tree.Nodes = append(tree.Nodes, argocdv3.ResourceNode{
+			ResourceRef: argocdv3.ResourceRef{
+				Group:     r.Group,
+				Version:   r.Version,
+				Kind:      r.Kind,
+				Namespace: r.Namespace,
+				Name:      r.Name,
+			},
+			Health: r.Health,
+		})
+	}
+	return tree, nil
+}
+
+func (c *kubeClient) GetManagedResources(_ context.Context, _ ApplicationRef) ([]*argocdv3.ResourceDiff, error) {
+	return nil, notSupported("get the managed resources of an application")
+}
+
+func (c *kubeClient) StreamPodLogs(_ context.Context, _ ApplicationRef, _ PodLogsOptions, _ func(LogEntry) bool) error {
+	return notSupported("get the logs of the pods of an application")
+}
+
+func (c *kubeClient) ListClusters(_ context.Context) (*argocdv3.ClusterList, error) {
+	return nil, notSupported("list the clusters")
+}
+
+func (c *kubeClient) SyncApplication(_ context.Context, _ ApplicationRef, _ SyncApplicationRequest) (*argocdv3.Application, error) {
+	return nil, notSupported("sync an application")
+}
+
+func (c *kubeClient) RollbackApplication(_ context.Context, _ ApplicationRef, _ RollbackApplicationRequest) (*argocdv3.Application, error) {
+	return nil, notSupported("rollback an application")
+}
+
+func (c *kubeClient) namespaceOr(namespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+	return c.namespace
+}
+
+func fromUnstructured(obj *unstructured.Unstructured, result any) error {
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), result); err != nil {
+		return fmt.Errorf("failed to convert %s '%s': %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// newKubeAPIError converts the errors returned by the Kubernetes API server into APIErrors,
+// so that the tools handle them as the errors returned by the Argo CD API server (eg: `404 Not Found`)
+func newKubeAPIError(err error) error {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return err
+	}
+	return &APIError{
+		StatusCode: int(status.Status().Code),
+		Message:    status.Status().Message,
+	}
+}
+
+// KubeInstanceURL returns a URL which identifies the applications in the given namespace of the cluster with the given configuration
+func KubeInstanceURL(cfg *rest.Config, namespace string) string {
+	return fmt.Sprintf("%s/apis/%s/namespaces/%s/applications", cfg.Host, applicationsGVR.GroupVersion(), namespace)
+}
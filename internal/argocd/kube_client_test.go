@@ -0,0 +1,374 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	testresources "github.com/codeready-toolchain/argocd-mcp/test/resources"
+)
+
+func TestKubeClient(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	t.Run("list applications", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		// when
+		apps, err := cl.ListApplications(context.Background(), ListApplicationsOptions{
+			Projects: []string{"team-a"},
+		})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, apps.Items, 1)
+		assert.Equal(t, "a-degraded-application", apps.Items[0].Name)
+		assert.Equal(t, health.HealthStatusDegraded, apps.Items[0].Status.Health.Status)
+	})
+
+	t.Run("list applications in another namespace", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		// when
+		apps, err := cl.ListApplications(context.Background(), ListApplicationsOptions{
+			AppNamespace: "team-a",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, apps.Items)
+	})
+
+	t.Run("get application", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		// when
+		app, err := cl.GetApplication(context.Background(), ApplicationRef{Name: "a-progressing-application"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "team-b", app.Spec.Project)
+	})
+
+	t.Run("application not found", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		// when
+		_, err := cl.GetApplication(context.Background(), ApplicationRef{Name: "unknown"})
+
+		// then
+		require.True(t, IsNotFound(err))
+	})
+
+	t.Run("list events", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		// when
+		events, err := cl.ListEvents(context.Background(), ApplicationRef{Name: "a-degraded-application"}, ListEventsOptions{})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, events.Items, 1)
+		assert.Equal(t, "OperationCompleted", events.Items[0].Reason)
+	})
+
+	t.Run("list projects", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		// when
+		projects, err := cl.ListProjects(context.Background())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, projects.Items, 1)
+		assert.Equal(t, "team-a", projects.Items[0].Name)
+	})
+
+	t.Run("watch applications", func(t *testing.T) {
+		// given
+		dyn := newFakeDynamicClient(t)
+		// the fake dynamic client only sends the events which happen after the watch started
+		started := make(chan struct{})
+		dyn.PrependWatchReactor("applications", func(action clienttesting.Action) (bool, watch.Interface, error) {
+			defer close(started)
+			w, err := dyn.Tracker().Watch(applicationsGVR, action.GetNamespace())
+			return true, w, err
+		})
+		cl := NewKubeClient(dyn, "argocd")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		events := make(chan argocdv3.ApplicationWatchEvent)
+		go func() {
+			_ = cl.WatchApplications(ctx, "", func(e argocdv3.ApplicationWatchEvent) bool {
+				events <- e
+				return e.Type != watch.Deleted
+			})
+		}()
+		select {
+		case <-started:
+		case <-ctx.Done():
+			t.Fatal("watch not started")
+		}
+
+		// when
+		err := dyn.Resource(applicationsGVR).Namespace("argocd").Delete(ctx, "a-degraded-application", metav1.DeleteOptions{})
+
+		// then
+		require.NoError(t, err)
+		select {
+		case e := <-events:
+			assert.Equal(t, watch.Deleted, e.Type)
+			assert.Equal(t, "a-degraded-application", e.Application.Name)
+		case <-ctx.Done():
+			t.Fatal("no event received")
+		}
+	})
+
+	t.Run("resource tree", func(t *testing.T) {
+		// given
+		dyn := newFakeDynamicClient(t)
+		app, err := dyn.Resource(applicationsGVR).Namespace("argocd").Get(context.Background(), "a-degraded-application", metav1.GetOptions{})
+		require.NoError(t, err)
+		err = unstructured.SetNestedSlice(app.Object, []any{
+			map[string]any{
+				"group":     "apps",
+				"version":   "v1",
+				"kind":      "Deployment",
+				"namespace": "team-a-dev",
+				"name":      "a-degraded-application",
+				"health": map[string]any{
+					"status":  "Degraded",
+					"message": "Deployment \"a-degraded-application\" exceeded its progress deadline",
+				},
+			},
+			map[string]any{
+				"version":   "v1",
+				"kind":      "Service",
+				"namespace": "team-a-dev",
+				"name":      "a-degraded-application",
+				"health": map[string]any{
+					"status": "Healthy",
+				},
+			},
+		}, "status", "resources")
+		require.NoError(t, err)
+		_, err = dyn.Resource(applicationsGVR).Namespace("argocd").Update(context.Background(), app, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		cl := NewKubeClient(dyn, "argocd")
+
+		// when
+		tree, err := getApplicationResourceTree(context.Background(), logger, cl, ApplicationResourceTreeInput{
+			Name:          "a-degraded-application",
+			UnhealthyOnly: true,
+		})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, tree.Nodes, 1)
+		assert.Equal(t, "Deployment", tree.Nodes[0].Kind)
+		require.NotNil(t, tree.Nodes[0].Health)
+		assert.Equal(t, "Degraded", tree.Nodes[0].Health.Status)
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		// when
+		_, err := cl.SyncApplication(context.Background(), ApplicationRef{Name: "a-degraded-application"}, SyncApplicationRequest{})
+
+		// then
+		require.ErrorIs(t, err, errNotSupported)
+		assert.EqualError(t, err, "sync an application: not supported when reading the Applications through the Kubernetes API")
+	})
+
+	t.Run("read tools", func(t *testing.T) {
+		// given
+		cl := NewKubeClient(newFakeDynamicClient(t), "argocd")
+
+		t.Run("unhealthy applications", func(t *testing.T) {
+			// when
+			unhealthyApps, err := listUnhealthyApplications(context.Background(), logger, cl, UnhealthyApplicationsInput{})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, []string{"argocd/a-degraded-application", "argocd/another-degraded-application"}, unhealthyApps.Health.Degraded)
+		})
+
+		t.Run("unhealthy application resources", func(t *testing.T) {
+			// when
+			_, err := listUnhealthyApplicationResources(context.Background(), logger, cl, ApplicationRef{Name: "a-degraded-application"})
+
+			// then
+			require.NoError(t, err)
+		})
+
+		t.Run("application history", func(t *testing.T) {
+			// when
+			_, err := listApplicationHistory(context.Background(), logger, cl, ApplicationRef{Name: "a-degraded-application"})
+
+			// then
+			require.NoError(t, err)
+		})
+	})
+}
+
+func TestNewKubeConfig(t *testing.T) {
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	err := os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://api.dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://api.prod.example.com:6443
+users:
+- name: user
+  user:
+    token: secure-token
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: user
+- name: prod
+  context:
+    cluster: prod
+    user: user
+current-context: dev
+`), 0o600)
+	require.NoError(t, err)
+
+	t.Run("current context", func(t *testing.T) {
+		// when
+		cfg, err := NewKubeConfig(KubeOptions{
+			Kubeconfig: kubeconfig,
+			Timeout:    time.Minute,
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.dev.example.com:6443", cfg.Host)
+		assert.Equal(t, time.Minute, cfg.Timeout)
+		assert.Equal(t, "https://api.dev.example.com:6443/apis/argoproj.io/v1alpha1/namespaces/argocd/applications", KubeInstanceURL(cfg, "argocd"))
+	})
+
+	t.Run("other context", func(t *testing.T) {
+		// when
+		cfg, err := NewKubeConfig(KubeOptions{
+			Kubeconfig: kubeconfig,
+			Context:    "prod",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.prod.example.com:6443", cfg.Host)
+	})
+
+	t.Run("unknown context", func(t *testing.T) {
+		// when
+		_, err := NewKubeConfig(KubeOptions{
+			Kubeconfig: kubeconfig,
+			Context:    "staging",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to load the Kubernetes configuration")
+	})
+}
+
+// newFakeDynamicClient returns a fake dynamic client with the test applications in the `argocd` namespace,
+// a project and an event of the `a-degraded-application` application
+func newFakeDynamicClient(t *testing.T) *dynamicfake.FakeDynamicClient {
+	apps, err := unmarshalApplicationList(testresources.ApplicationsStr)
+	require.NoError(t, err)
+	objs := []runtime.Object{}
+	for _, app := range apps.Items {
+		app.APIVersion = argocdv3.SchemeGroupVersion.String()
+		app.Kind = "Application"
+		objs = append(objs, toUnstructured(t, &app))
+	}
+	objs = append(objs,
+		toUnstructured(t, &argocdv3.AppProject{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: argocdv3.SchemeGroupVersion.String(),
+				Kind:       "AppProject",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "team-a",
+				Namespace: "argocd",
+			},
+		}),
+		toUnstructured(t, &corev1.Event{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Event",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "a-degraded-application.1",
+				Namespace: "argocd",
+			},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Application",
+				Name: "a-degraded-application",
+			},
+			Reason: "OperationCompleted",
+		}),
+		toUnstructured(t, &corev1.Event{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Event",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "a-progressing-application.1",
+				Namespace: "argocd",
+			},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Application",
+				Name: "a-progressing-application",
+			},
+			Reason: "ResourceUpdated",
+		}),
+	)
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		applicationsGVR: "ApplicationList",
+		appProjectsGVR:  "AppProjectList",
+		eventsGVR:       "EventList",
+	}, objs...)
+}
+
+func toUnstructured(t *testing.T, obj any) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{
+		Object: content,
+	}
+}
@@ -0,0 +1,164 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var PodLogsTool = &mcp.Tool{
+	Name: "podLogs",
+	Description: "return the most recent logs of a pod of an Argo CD Application, or of the pods of one of its resources (eg: a StatefulSet). " +
+		"Use it to find out why a pod is crashing (use `previous` to get the logs of the last terminated container)",
+	InputSchema:  PodLogsInputSchema,
+	OutputSchema: PodLogsOutputSchema,
+}
+
+type PodLogsInput struct {
+	Name          string `json:"name" jsonschema:"the name of the Argo CD Application which owns the pod"`
+	AppNamespace  string `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance      string `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	PodName       string `json:"podName,omitempty" jsonschema:"the name of the pod to get the logs of (required unless 'resourceKind' and 'resourceName' are specified)"`
+	ResourceGroup string `json:"resourceGroup,omitempty" jsonschema:"the API group of the resource to get the logs of the pods of (eg: 'apps')"`
+	ResourceKind  string `json:"resourceKind,omitempty" jsonschema:"the kind of the resource to get the logs of the pods of (eg: 'Deployment')"`
+	ResourceName  string `json:"resourceName,omitempty" jsonschema:"the name of the resource to get the logs of the pods of"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"the namespace of the pod or resource"`
+	Container     string `json:"container,omitempty" jsonschema:"the name of the container to get the logs of (defaults to all containers)"`
+	TailLines     int64  `json:"tailLines,omitempty" jsonschema:"the number of lines to return from the end of the logs (defaults to 100)"`
+	SinceSeconds  int64  `json:"sinceSeconds,omitempty" jsonschema:"only return the logs of the last seconds"`
+	Previous      bool   `json:"previous,omitempty" jsonschema:"return the logs of the previous (terminated) container, eg: after a crash"`
+	Filter        string `json:"filter,omitempty" jsonschema:"only return the lines which contain this text"`
+}
+
+var PodLogsInputSchema, _ = jsonschema.For[PodLogsInput](&jsonschema.ForOptions{})
+
+type PodLogsOutput PodLogs
+
+var PodLogsOutputSchema, _ = jsonschema.For[PodLogsOutput](&jsonschema.ForOptions{})
+
+func PodLogsToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[PodLogsInput, PodLogsOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in PodLogsInput) (*mcp.CallToolResult, PodLogsOutput, error) {
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, PodLogsOutput{}, err
+		}
+		logs, err := getPodLogs(ctx, logger, cl, in)
+		if err != nil {
+			return nil, PodLogsOutput{}, err
+		}
+		return nil, PodLogsOutput(logs), nil
+	}
+}
+
+// PodLogs the most recent log lines, from the oldest to the most recent
+type PodLogs struct {
+	Lines []LogLine `json:"lines"`
+	// Truncated true if the oldest lines were dropped because the logs exceeded the maximum size
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// LogLine a line of logs of a pod
+type LogLine struct {
+	PodName   string `json:"podName"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Content   string `json:"content"`
+}
+
+const (
+	defaultPodLogsTailLines = 100
+	// maxPodLogsBytes the maximum size of the content of the returned log lines
+	maxPodLogsBytes = 32 * 1024
+)
+
+func getPodLogs(ctx context.Context, logger *slog.Logger, cl Client, in PodLogsInput) (PodLogs, error) {
+	if in.PodName == "" && (in.ResourceKind == "" || in.ResourceName == "") {
+		return PodLogs{}, fmt.Errorf("either the 'podName' or the 'resourceKind' and 'resourceName' must be specified")
+	}
+	tailLines := in.TailLines
+	if tailLines <= 0 {
+		tailLines = defaultPodLogsTailLines
+	}
+	result := PodLogs{
+		Lines: []LogLine{},
+	}
+	size := 0
+	// dropped the number of oldest lines which no longer fit in the result
+	dropped := 0
+	ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+	if err != nil {
+		return PodLogs{}, err
+	}
+	ref, err = resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return PodLogs{}, err
+	}
+	err = cl.StreamPodLogs(ctx, ref, PodLogsOptions{
+		PodName:      in.PodName,
+		Group:        in.ResourceGroup,
+		Kind:         in.ResourceKind,
+		ResourceName: in.ResourceName,
+		Namespace:    in.Namespace,
+		Container:    in.Container,
+		TailLines:    tailLines,
+		SinceSeconds: in.SinceSeconds,
+		Previous:     in.Previous,
+		Filter:       in.Filter,
+	}, func(e LogEntry) bool {
+		content := e.Content
+		if len(content) > maxPodLogsBytes {
+			content = truncateUTF8(content, maxPodLogsBytes)
+			result.Truncated = true
+		}
+		result.Lines = append(result.Lines, LogLine{
+			PodName:   e.PodName,
+			Timestamp: e.TimeStampStr,
+			Content:   content,
+		})
+		size += len(content)
+		// keep the most recent lines only
+		for size > maxPodLogsBytes {
+			size -= len(result.Lines[dropped].Content)
+			dropped++
+			result.Truncated = true
+		}
+		// copy the kept lines once most of them were dropped, so that the dropped ones can be garbage collected
+		if dropped > len(result.Lines)/2 {
+			result.Lines = slices.Clone(result.Lines[dropped:])
+			dropped = 0
+		}
+		return true
+	})
+	if err != nil {
+		return PodLogs{}, fmt.Errorf("failed to get pod logs of application '%s': %w", ref, err)
+	}
+	if dropped > 0 {
+		result.Lines = slices.Clone(result.Lines[dropped:])
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		resultStr, err := json.Marshal(result)
+		if err != nil {
+			logger.Error("failed to convert pod logs to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "podLogs", "app", ref.String(), "result", string(resultStr))
+	}
+	return result, nil
+}
+
+// truncateUTF8 returns the longest prefix of the given content which is at most the given number of bytes
+// and does not end in the middle of a multi-byte character
+func truncateUTF8(content string, maxBytes int) string {
+	if len(content) <= maxBytes {
+		return content
+	}
+	i := maxBytes
+	for i > 0 && !utf8.RuneStart(content[i]) {
+		i--
+	}
+	return content[:i]
+}
@@ -0,0 +1,211 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPodLogs(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	t.Run("logs of a pod", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		logs, err := getPodLogs(context.Background(), logger, cl, PodLogsInput{
+			Name:      "example",
+			PodName:   "example-0",
+			Namespace: "example-ns",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, PodLogsOptions{
+			PodName:   "example-0",
+			Namespace: "example-ns",
+			TailLines: 100,
+		}, cl.PodLogsRequests["argocd/example"])
+		require.Len(t, logs.Lines, 6)
+		assert.False(t, logs.Truncated)
+		assert.Equal(t, LogLine{
+			PodName:   "example-0",
+			Timestamp: "2025-08-07T14:01:12.104938Z",
+			Content:   "2025-08-07 14:01:12.104 INFO  starting example server version=1.2.3",
+		}, logs.Lines[0])
+		assert.Equal(t, LogLine{
+			PodName:   "example-0",
+			Timestamp: "2025-08-07T14:01:15.003120Z",
+			Content:   "2025-08-07 14:01:15.003 FATAL exiting: missing database credentials",
+		}, logs.Lines[5])
+	})
+
+	t.Run("filtered logs of the previous containers of a resource", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		logs, err := getPodLogs(context.Background(), logger, cl, PodLogsInput{
+			Name:          "example",
+			ResourceGroup: "apps",
+			ResourceKind:  "StatefulSet",
+			ResourceName:  "example",
+			Container:     "example",
+			TailLines:     20,
+			SinceSeconds:  3600,
+			Previous:      true,
+			Filter:        "ERROR",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, PodLogsOptions{
+			Group:        "apps",
+			Kind:         "StatefulSet",
+			ResourceName: "example",
+			Container:    "example",
+			TailLines:    20,
+			SinceSeconds: 3600,
+			Previous:     true,
+			Filter:       "ERROR",
+		}, cl.PodLogsRequests["argocd/example"])
+		assert.Equal(t, PodLogs{
+			Lines: []LogLine{
+				{
+					PodName:   "example-0",
+					Timestamp: "2025-08-07T14:01:15.002310Z",
+					Content:   `2025-08-07 14:01:15.002 ERROR failed to read secret example-secret: secrets "example-secret" not found`,
+				},
+			},
+		}, logs)
+	})
+
+	t.Run("truncated logs", func(t *testing.T) {
+		// given
+		cl := &largeLogsClient{}
+
+		// when
+		logs, err := getPodLogs(context.Background(), logger, cl, PodLogsInput{
+			Name:    "example",
+			PodName: "example-0",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, logs.Truncated)
+		// only the most recent lines are kept
+		require.Len(t, logs.Lines, 3)
+		assert.True(t, strings.HasPrefix(logs.Lines[0].Content, "line 2 "))
+		assert.True(t, strings.HasPrefix(logs.Lines[2].Content, "line 4 "))
+	})
+
+	t.Run("truncated multi-byte line", func(t *testing.T) {
+		// given
+		cl := &linesLogsClient{
+			lines: []string{"x" + strings.Repeat("é", maxPodLogsBytes/2)}, // the limit falls in the middle of an `é`
+		}
+
+		// when
+		logs, err := getPodLogs(context.Background(), logger, cl, PodLogsInput{
+			Name:    "example",
+			PodName: "example-0",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, logs.Truncated)
+		require.Len(t, logs.Lines, 1)
+		assert.True(t, utf8.ValidString(logs.Lines[0].Content))
+		assert.Len(t, logs.Lines[0].Content, maxPodLogsBytes-1)
+	})
+
+	t.Run("missing pod or resource", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := getPodLogs(context.Background(), logger, cl, PodLogsInput{
+			Name:         "example",
+			ResourceName: "example",
+		})
+
+		// then
+		require.EqualError(t, err, "either the 'podName' or the 'resourceKind' and 'resourceName' must be specified")
+	})
+
+	t.Run("unknown pod", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := getPodLogs(context.Background(), logger, cl, PodLogsInput{
+			Name:    "example",
+			PodName: "unknown",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to get pod logs of application 'argocd/example'")
+		assert.True(t, IsNotFound(err))
+	})
+
+	t.Run("argocd error", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := getPodLogs(context.Background(), logger, cl, PodLogsInput{
+			Name:    "example-error",
+			PodName: "example-0",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to get application 'example-error' from Argo CD")
+	})
+}
+
+// largeLogsClient a client which returns 5 log lines of 10KiB each
+type largeLogsClient struct {
+	FakeArgoCDClient
+}
+
+func (c *largeLogsClient) StreamPodLogs(_ context.Context, _ ApplicationRef, _ PodLogsOptions, handle func(LogEntry) bool) error {
+	for i := range 5 {
+		content := fmt.Sprintf("line %d ", i)
+		if !handle(LogEntry{
+			PodName: "example-0",
+			Content: content + strings.Repeat("x", 10*1024-len(content)),
+		}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// linesLogsClient a client which returns the given log lines
+type linesLogsClient struct {
+	FakeArgoCDClient
+	lines []string
+}
+
+func (c *linesLogsClient) StreamPodLogs(_ context.Context, _ ApplicationRef, _ PodLogsOptions, handle func(LogEntry) bool) error {
+	for _, l := range c.lines {
+		if !handle(LogEntry{
+			PodName: "example-0",
+			Content: l,
+		}) {
+			return nil
+		}
+	}
+	return nil
+}
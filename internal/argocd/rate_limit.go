@@ -0,0 +1,119 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitOptions the options to limit the load on the Argo CD API server
+type RateLimitOptions struct {
+	// QPS the maximum number of requests per second, on average (0 for no limit)
+	QPS float64
+	// Burst the maximum number of requests sent at once when no request was sent for a while
+	// (defaults to the QPS rounded up, when set)
+	Burst int
+	// MaxInFlight the maximum number of requests in flight, including the reading of their response body (0 for no limit)
+	MaxInFlight int
+}
+
+type waitNotifierKey struct{}
+
+// WithWaitNotifier returns a context in which the given func is called with a message when a request to Argo CD
+// is delayed by the rate limit or by the max number of requests in flight (eg: to notify the progress to the MCP client)
+func WithWaitNotifier(ctx context.Context, notify func(ctx context.Context, message string)) context.Context {
+	return context.WithValue(ctx, waitNotifierKey{}, notify)
+}
+
+func notifyWait(ctx context.Context, message string) {
+	if notify, ok := ctx.Value(waitNotifierKey{}).(func(context.Context, string)); ok {
+		notify(ctx, message)
+	}
+}
+
+// limiter delays the requests to Argo CD according to the rate limit options
+type limiter struct {
+	rate   *rate.Limiter
+	slots  chan struct{}
+	logger *slog.Logger
+}
+
+// newLimiter returns a limiter with the given options, or nil if the options have no limit
+func newLimiter(opts RateLimitOptions, logger *slog.Logger) (*limiter, error) {
+	if opts.QPS < 0 || opts.Burst < 0 || opts.MaxInFlight < 0 {
+		return nil, fmt.Errorf("invalid rate limit settings: the QPS, burst and max in flight cannot be negative")
+	}
+	if opts.QPS == 0 && opts.MaxInFlight == 0 {
+		return nil, nil
+	}
+	l := &limiter{
+		logger: logger,
+	}
+	if opts.QPS > 0 {
+		burst := opts.Burst
+		if burst == 0 {
+			burst = int(math.Ceil(opts.QPS))
+		}
+		l.rate = rate.NewLimiter(rate.Limit(opts.QPS), burst)
+	}
+	if opts.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, opts.MaxInFlight)
+	}
+	return l, nil
+}
+
+// wait waits until the request with the given method and path can be sent according to the rate limit,
+// and until there is a free slot for it. Returns the func to free the slot once the response was read.
+func (l *limiter) wait(ctx context.Context, method string, path string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if l.rate != nil {
+		r := l.rate.Reserve()
+		if delay := r.Delay(); delay > 0 {
+			l.logger.InfoContext(ctx, "delaying request to Argo CD to stay within the rate limit", "method", method, "path", path, "delay", delay.String())
+			notifyWait(ctx, fmt.Sprintf("waiting %s to stay within the rate limit of Argo CD", delay.Round(time.Millisecond)))
+			if err := sleep(ctx, delay); err != nil {
+				r.Cancel()
+				return nil, err
+			}
+		}
+	}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			l.logger.InfoContext(ctx, "delaying request to Argo CD until another request completes", "method", method, "path", path, "max-in-flight", cap(l.slots))
+			notifyWait(ctx, fmt.Sprintf("waiting for one of the %d requests in flight to Argo CD to complete", cap(l.slots)))
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		once := sync.Once{}
+		return func() {
+			once.Do(func() {
+				<-l.slots
+			})
+		}, nil
+	}
+	return func() {}, nil
+}
+
+// releasingBody calls the release func when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,195 @@
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRateLimit(t *testing.T) {
+
+	// a minimal Argo CD server which records the max number of concurrent requests,
+	// and which holds each request until the given channel is closed
+	newServer := func(t *testing.T, hold <-chan struct{}) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+		inFlight := &atomic.Int32{}
+		maxInFlight := &atomic.Int32{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			<-hold
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}))
+		t.Cleanup(srv.Close)
+		return srv, inFlight, maxInFlight
+	}
+
+	// notifications returns a context which records the wait notifications, and a func to get them
+	notifications := func() (context.Context, func() []string) {
+		mu := sync.Mutex{}
+		messages := []string{}
+		ctx := WithWaitNotifier(context.Background(), func(_ context.Context, message string) {
+			mu.Lock()
+			defer mu.Unlock()
+			messages = append(messages, message)
+		})
+		return ctx, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string{}, messages...)
+		}
+	}
+
+	t.Run("rate limit", func(t *testing.T) {
+		// given
+		hold := make(chan struct{})
+		close(hold)
+		srv, _, _ := newServer(t, hold)
+		logs := &bytes.Buffer{}
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			RateLimit: RateLimitOptions{
+				QPS:   20,
+				Burst: 1,
+			},
+			Logger: slog.New(slog.NewTextHandler(logs, nil)),
+		})
+		require.NoError(t, err)
+		ctx, messages := notifications()
+		start := time.Now()
+
+		// when
+		for range 3 {
+			_, err = cl.ListProjects(ctx)
+			require.NoError(t, err)
+		}
+
+		// then
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond) // 2 delays of ~50ms
+		assert.Len(t, messages(), 2)
+		assert.Contains(t, messages()[0], "to stay within the rate limit of Argo CD")
+		assert.Equal(t, 2, strings.Count(logs.String(), `msg="delaying request to Argo CD to stay within the rate limit" method=GET path=api/v1/projects`))
+	})
+
+	t.Run("default burst", func(t *testing.T) {
+		// when
+		l, err := newLimiter(RateLimitOptions{QPS: 2.5}, slog.New(slog.DiscardHandler))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 3, l.rate.Burst())
+		assert.Nil(t, l.slots)
+	})
+
+	t.Run("max in flight", func(t *testing.T) {
+		// given
+		hold := make(chan struct{})
+		srv, inFlight, maxInFlight := newServer(t, hold)
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			RateLimit: RateLimitOptions{
+				MaxInFlight: 2,
+			},
+		})
+		require.NoError(t, err)
+		ctx, messages := notifications()
+
+		// when
+		wg := sync.WaitGroup{}
+		errs := make(chan error, 5)
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := cl.ListProjects(ctx)
+				errs <- err
+			}()
+		}
+		require.Eventually(t, func() bool {
+			return inFlight.Load() == 2 && len(messages()) == 3
+		}, time.Second, time.Millisecond)
+		close(hold)
+		wg.Wait()
+		close(errs)
+
+		// then
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), maxInFlight.Load())
+		assert.Equal(t, "waiting for one of the 2 requests in flight to Argo CD to complete", messages()[0])
+	})
+
+	t.Run("context done while waiting", func(t *testing.T) {
+		// given
+		hold := make(chan struct{})
+		defer close(hold)
+		srv, inFlight, _ := newServer(t, hold)
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			RateLimit: RateLimitOptions{
+				MaxInFlight: 1,
+			},
+		})
+		require.NoError(t, err)
+		go func() {
+			_, _ = cl.ListProjects(context.Background())
+		}()
+		require.Eventually(t, func() bool {
+			return inFlight.Load() == 1
+		}, time.Second, time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// when
+		_, err = cl.ListProjects(ctx)
+
+		// then
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("slot released after an error", func(t *testing.T) {
+		// given
+		cl, err := NewClient("http://127.0.0.1:1", StaticToken("secure-token"), ClientOptions{
+			RateLimit: RateLimitOptions{
+				MaxInFlight: 1,
+			},
+		})
+		require.NoError(t, err)
+
+		for range 2 {
+			// when
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			_, err = cl.ListProjects(ctx)
+			cancel()
+
+			// then the connection failed, but the request did not wait for a free slot
+			require.Error(t, err)
+			require.NotErrorIs(t, err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		// when
+		_, err := NewClient("http://argocd.example.com", StaticToken("secure-token"), ClientOptions{
+			RateLimit: RateLimitOptions{
+				QPS: -1,
+			},
+		})
+
+		// then
+		require.EqualError(t, err, "invalid rate limit settings: the QPS, burst and max in flight cannot be negative")
+	})
+}
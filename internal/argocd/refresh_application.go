@@ -0,0 +1,106 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var RefreshApplicationTool = &mcp.Tool{
+	Name:         "refreshApplication",
+	Description:  "refresh an Argo CD Application (ie, compare its live state with the target state in Git again) and return its sync and health status",
+	InputSchema:  RefreshApplicationInputSchema,
+	OutputSchema: RefreshApplicationOutputSchema,
+}
+
+type RefreshApplicationInput struct {
+	Name         string `json:"name" jsonschema:"the name of the Argo CD Application to refresh"`
+	AppNamespace string `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance     string `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	Hard         bool   `json:"hard,omitempty" jsonschema:"perform a hard refresh, which also invalidates the cache of the generated manifests"`
+}
+
+var RefreshApplicationInputSchema, _ = jsonschema.For[RefreshApplicationInput](&jsonschema.ForOptions{})
+
+type RefreshApplicationOutput RefreshedApplication
+
+var RefreshApplicationOutputSchema, _ = jsonschema.For[RefreshApplicationOutput](&jsonschema.ForOptions{})
+
+func RefreshApplicationToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[RefreshApplicationInput, RefreshApplicationOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in RefreshApplicationInput) (*mcp.CallToolResult, RefreshApplicationOutput, error) {
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, RefreshApplicationOutput{}, err
+		}
+		ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+		if err != nil {
+			return nil, RefreshApplicationOutput{}, err
+		}
+		app, err := refreshApplication(ctx, logger, cl, ref, in.Hard)
+		if err != nil {
+			return nil, RefreshApplicationOutput{}, err
+		}
+		return nil, RefreshApplicationOutput(app), nil
+	}
+}
+
+type RefreshedApplication struct {
+	Name         string        `json:"name"`
+	Namespace    string        `json:"namespace,omitempty"`
+	Health       HealthSummary `json:"health"`
+	Sync         SyncSummary   `json:"sync"`
+	ReconciledAt string        `json:"reconciledAt,omitempty"`
+}
+
+// HealthSummary the health status of an application
+type HealthSummary struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// SyncSummary the sync status of an application
+type SyncSummary struct {
+	Status   string `json:"status"`
+	Revision string `json:"revision,omitempty"`
+}
+
+func refreshApplication(ctx context.Context, logger *slog.Logger, cl Client, ref ApplicationRef, hard bool) (RefreshedApplication, error) {
+	refresh := argocdv3.RefreshTypeNormal
+	if hard {
+		refresh = argocdv3.RefreshTypeHard
+	}
+	ref, err := resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return RefreshedApplication{}, err
+	}
+	app, err := cl.RefreshApplication(ctx, ref, refresh)
+	if err != nil {
+		return RefreshedApplication{}, fmt.Errorf("failed to refresh application '%s': %w", ref, err)
+	}
+	result := RefreshedApplication{
+		Name:      app.Name,
+		Namespace: app.Namespace,
+		Health: HealthSummary{
+			Status:  string(app.Status.Health.Status),
+			Message: app.Status.Health.Message,
+		},
+		Sync: SyncSummary{
+			Status:   string(app.Status.Sync.Status),
+			Revision: app.Status.Sync.Revision,
+		},
+		ReconciledAt: formatTime(app.Status.ReconciledAt),
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		resultStr, err := json.Marshal(result)
+		if err != nil {
+			logger.Error("failed to convert refreshed application to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "refreshApplication", "app", ref.String(), "result", string(resultStr))
+	}
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshApplication(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	testdata := []struct {
+		name            string
+		hard            bool
+		expectedRefresh argocdv3.RefreshType
+	}{
+		{
+			name:            "normal",
+			hard:            false,
+			expectedRefresh: argocdv3.RefreshTypeNormal,
+		},
+		{
+			name:            "hard",
+			hard:            true,
+			expectedRefresh: argocdv3.RefreshTypeHard,
+		},
+	}
+
+	for _, td := range testdata {
+		t.Run(td.name, func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			app, err := refreshApplication(context.Background(), logger, cl, ApplicationRef{Name: "example"}, td.hard)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, td.expectedRefresh, cl.Refreshes["argocd/example"])
+			assert.Equal(t, RefreshedApplication{
+				Name:      "example",
+				Namespace: "argocd",
+				Health: HealthSummary{
+					Status: "Progressing",
+				},
+				Sync: SyncSummary{
+					Status:   "OutOfSync",
+					Revision: "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+				},
+				ReconciledAt: "2025-08-07T13:48:00Z",
+			}, app)
+		})
+	}
+
+	t.Run("same name in several namespaces", func(t *testing.T) {
+
+		t.Run("ambiguous name", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			_, err := refreshApplication(context.Background(), logger, cl, ApplicationRef{Name: "example-duplicate"}, false)
+
+			// then
+			require.EqualError(t, err, "application name 'example-duplicate' is ambiguous, it matches team-a/example-duplicate, team-b/example-duplicate: specify the 'appNamespace' or use a namespace-qualified name")
+			assert.Empty(t, cl.Refreshes)
+		})
+
+		t.Run("name and namespace", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			app, err := refreshApplication(context.Background(), logger, cl, ApplicationRef{Name: "example-duplicate", Namespace: "team-b"}, false)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "team-b", app.Namespace)
+			assert.Equal(t, map[string]argocdv3.RefreshType{
+				"team-b/example-duplicate": argocdv3.RefreshTypeNormal,
+			}, cl.Refreshes)
+		})
+	})
+
+	t.Run("argocd error", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := refreshApplication(context.Background(), logger, cl, ApplicationRef{Name: "example-error"}, false)
+
+		// then
+		require.ErrorContains(t, err, "failed to get application 'example-error' from Argo CD")
+	})
+}
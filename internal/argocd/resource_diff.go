@@ -0,0 +1,241 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/yaml"
+)
+
+var ResourceDiffTool = &mcp.Tool{
+	Name: "resourceDiff",
+	Description: "show the differences between the live state and the desired state of the OutOfSync resources of an Argo CD Application, as unified diffs of their YAML manifests. " +
+		"Lines starting with `-` are only in the live state, lines starting with `+` are only in the desired state. " +
+		"When the diffs are too long, the diffs of some resources are left out (see `omitted`): use the resource filters to get them",
+	InputSchema:  ResourceDiffInputSchema,
+	OutputSchema: ResourceDiffOutputSchema,
+}
+
+type ResourceDiffInput struct {
+	Name              string `json:"name" jsonschema:"the name of the Argo CD Application to get the diffs of"`
+	AppNamespace      string `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance          string `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	Fresh             bool   `json:"fresh,omitempty" jsonschema:"bypass the cache and fetch the latest state from Argo CD (optional, only needed right after a change which was not made with this server)"`
+	ResourceGroup     string `json:"resourceGroup,omitempty" jsonschema:"the API group of the resources to get the diff of"`
+	ResourceKind      string `json:"resourceKind,omitempty" jsonschema:"the kind of the resources to get the diff of"`
+	ResourceNamespace string `json:"resourceNamespace,omitempty" jsonschema:"the namespace of the resources to get the diff of"`
+	ResourceName      string `json:"resourceName,omitempty" jsonschema:"the name of the resources to get the diff of"`
+	MaxLines          int    `json:"maxLines,omitempty" jsonschema:"the maximum number of lines of each diff (defaults to 100)"`
+	MaxTotalLines     int    `json:"maxTotalLines,omitempty" jsonschema:"the maximum number of lines of all the diffs together (defaults to 1000), the diffs of the other resources are left out"`
+}
+
+var ResourceDiffInputSchema, _ = jsonschema.For[ResourceDiffInput](&jsonschema.ForOptions{})
+
+type ResourceDiffOutput ResourceDiffs
+
+var ResourceDiffOutputSchema, _ = jsonschema.For[ResourceDiffOutput](&jsonschema.ForOptions{})
+
+func ResourceDiffToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[ResourceDiffInput, ResourceDiffOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in ResourceDiffInput) (*mcp.CallToolResult, ResourceDiffOutput, error) {
+		if in.Fresh {
+			ctx = WithoutCache(ctx)
+		}
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, ResourceDiffOutput{}, err
+		}
+		diffs, err := listResourceDiffs(ctx, logger, cl, in)
+		if err != nil {
+			return nil, ResourceDiffOutput{}, err
+		}
+		return nil, ResourceDiffOutput(diffs), nil
+	}
+}
+
+type ResourceDiffs struct {
+	Resources []ResourceDiff `json:"resources"`
+	// Omitted the number of resources whose diff was left out because of the `maxTotalLines`
+	Omitted int `json:"omitted,omitempty"`
+}
+
+// ResourceDiff the diff between the live and desired states of a resource
+type ResourceDiff struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Status `Modified`, `Missing` (not in the cluster) or `Extra` (not in Git)
+	Status    string `json:"status"`
+	Diff      string `json:"diff"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+const (
+	defaultMaxDiffLines      = 100
+	defaultMaxTotalDiffLines = 1000
+)
+
+func listResourceDiffs(ctx context.Context, logger *slog.Logger, cl Client, in ResourceDiffInput) (ResourceDiffs, error) {
+	// use the namespace of the application, in case it was not specified
+	ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+	if err != nil {
+		return ResourceDiffs{}, err
+	}
+	ref, err = resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return ResourceDiffs{}, err
+	}
+	resources, err := cl.GetManagedResources(ctx, ref)
+	if err != nil {
+		return ResourceDiffs{}, fmt.Errorf("failed to get managed resources of application '%s': %w", ref, err)
+	}
+	maxLines := in.MaxLines
+	if maxLines <= 0 {
+		maxLines = defaultMaxDiffLines
+	}
+	remainingLines := in.MaxTotalLines
+	if remainingLines <= 0 {
+		remainingLines = defaultMaxTotalDiffLines
+	}
+	diffs := ResourceDiffs{
+		Resources: []ResourceDiff{},
+	}
+	for _, r := range resources {
+		if !r.Modified ||
+			(in.ResourceGroup != "" && r.Group != in.ResourceGroup) ||
+			(in.ResourceKind != "" && r.Kind != in.ResourceKind) ||
+			(in.ResourceNamespace != "" && r.Namespace != in.ResourceNamespace) ||
+			(in.ResourceName != "" && r.Name != in.ResourceName) {
+			continue
+		}
+		if remainingLines == 0 {
+			diffs.Omitted++
+			continue
+		}
+		d, err := newResourceDiff(r, min(maxLines, remainingLines))
+		if err != nil {
+			return ResourceDiffs{}, fmt.Errorf("failed to compute the diff of %s '%s': %w", r.Kind, r.Name, err)
+		}
+		remainingLines -= len(splitLines(d.Diff))
+		diffs.Resources = append(diffs.Resources, d)
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		diffsStr, err := json.Marshal(diffs)
+		if err != nil {
+			logger.Error("failed to convert resource diffs to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "resourceDiff", "app", ref.String(), "result", string(diffsStr))
+	}
+	return diffs, nil
+}
+
+// newResourceDiff returns the diff between the normalized live state and the predicted live (or target) state of the given resource.
+// Note: the ignored differences of the application are already removed from these states by Argo CD.
+func newResourceDiff(r *argocdv3.ResourceDiff, maxLines int) (ResourceDiff, error) {
+	desiredState := r.PredictedLiveState
+	if desiredState == "" || desiredState == "null" {
+		desiredState = r.TargetState
+	}
+	live, err := unmarshalState(r.NormalizedLiveState)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	desired, err := unmarshalState(desiredState)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	status := "Modified"
+	switch {
+	case live == nil:
+		status = "Missing"
+	case desired == nil:
+		status = "Extra"
+	}
+	liveYAML, err := marshalState(live)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	desiredYAML, err := marshalState(desired)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(liveYAML),
+		B:        splitLines(desiredYAML),
+		FromFile: "live",
+		ToFile:   "desired",
+		Context:  3,
+	})
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	result := ResourceDiff{
+		Group:     r.Group,
+		Kind:      r.Kind,
+		Namespace: r.Namespace,
+		Name:      r.Name,
+		Status:    status,
+		Diff:      diff,
+	}
+	if lines := splitLines(diff); len(lines) > maxLines {
+		result.Diff = strings.Join(lines[:maxLines], "")
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+// unmarshalState unmarshals the given JSON state and removes the fields which
+// are not relevant in a diff (eg: `status`, `metadata.managedFields`, etc.)
+func unmarshalState(state string) (map[string]any, error) {
+	if state == "" {
+		return nil, nil
+	}
+	obj := map[string]any{}
+	if err := json.Unmarshal([]byte(state), &obj); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource state: %w", err)
+	}
+	if obj == nil { // `null` state
+		return nil, nil
+	}
+	delete(obj, "status")
+	if metadata, ok := obj["metadata"].(map[string]any); ok {
+		for _, f := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp"} {
+			delete(metadata, f)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]any); ok {
+			delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+	return obj, nil
+}
+
+func marshalState(obj map[string]any) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal resource state: %w", err)
+	}
+	return string(data), nil
+}
+
+// splitLines splits the given text in lines, keeping their trailing `\n`
+// (unlike `difflib.SplitLines`, it does not add an extra empty line at the end)
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,152 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListResourceDiffs(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	externalSecretDiff := ResourceDiff{
+		Group:     "external-secrets.io",
+		Kind:      "ExternalSecret",
+		Namespace: "example-ns",
+		Name:      "example-secret",
+		Status:    "Missing",
+		Diff: `--- live
++++ desired
+@@ -0,0 +1,16 @@
++apiVersion: external-secrets.io/v1beta1
++kind: ExternalSecret
++metadata:
++  labels:
++    app.kubernetes.io/instance: example
++  name: example-secret
++  namespace: example-ns
++spec:
++  dataFrom:
++  - extract:
++      key: example
++  secretStoreRef:
++    kind: ClusterSecretStore
++    name: vault
++  target:
++    name: example-secret
+`,
+	}
+	tektonConfigDiff := ResourceDiff{
+		Group:  "operator.tekton.dev",
+		Kind:   "TektonConfig",
+		Name:   "config",
+		Status: "Modified",
+		// the `/spec/pipeline/enable-api-fields` difference is ignored (and removed from the states by Argo CD)
+		Diff: `--- live
++++ desired
+@@ -8,6 +8,6 @@
+   pipeline: {}
+   profile: all
+   pruner:
+-    keep: 100
++    keep: 10
+     schedule: 0 8 * * *
+   targetNamespace: openshift-pipelines
+`,
+	}
+
+	t.Run("all resources", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		diffs, err := listResourceDiffs(context.Background(), logger, cl, ResourceDiffInput{
+			Name: "example",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ResourceDiffs{
+			Resources: []ResourceDiff{externalSecretDiff, tektonConfigDiff},
+		}, diffs)
+	})
+
+	t.Run("selected resource", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		diffs, err := listResourceDiffs(context.Background(), logger, cl, ResourceDiffInput{
+			Name:         "example",
+			ResourceKind: "TektonConfig",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ResourceDiffs{
+			Resources: []ResourceDiff{tektonConfigDiff},
+		}, diffs)
+	})
+
+	t.Run("truncated diff", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		diffs, err := listResourceDiffs(context.Background(), logger, cl, ResourceDiffInput{
+			Name:         "example",
+			ResourceKind: "ExternalSecret",
+			MaxLines:     5,
+		})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, diffs.Resources, 1)
+		assert.Equal(t, `--- live
++++ desired
+@@ -0,0 +1,16 @@
++apiVersion: external-secrets.io/v1beta1
++kind: ExternalSecret
+`, diffs.Resources[0].Diff)
+		assert.True(t, diffs.Resources[0].Truncated)
+	})
+
+	t.Run("total lines", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		diffs, err := listResourceDiffs(context.Background(), logger, cl, ResourceDiffInput{
+			Name:          "example",
+			MaxTotalLines: 10,
+		})
+
+		// then the first diff is truncated to the total lines, and the other one is left out
+		require.NoError(t, err)
+		require.Len(t, diffs.Resources, 1)
+		assert.Equal(t, "ExternalSecret", diffs.Resources[0].Kind)
+		assert.Len(t, splitLines(diffs.Resources[0].Diff), 10)
+		assert.True(t, diffs.Resources[0].Truncated)
+		assert.Equal(t, 1, diffs.Omitted)
+	})
+
+	t.Run("argocd error", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := listResourceDiffs(context.Background(), logger, cl, ResourceDiffInput{
+			Name: "example-error",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to get application 'example-error' from Argo CD")
+	})
+}
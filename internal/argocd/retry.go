@@ -0,0 +1,118 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryOptions the options to retry the idempotent requests which failed with a transient error
+type RetryOptions struct {
+	// MaxRetries the maximum number of retries after the first attempt (0 to disable the retries)
+	MaxRetries int
+	// InitialBackoff the delay before the first retry, which is doubled after each retry (with some jitter)
+	InitialBackoff time.Duration
+	// MaxBackoff the maximum delay between 2 attempts. If the server asks for a longer delay
+	// with the `Retry-After` header, then the request is not retried.
+	MaxBackoff time.Duration
+}
+
+type retryDeadlineKey struct{}
+
+// WithRetryDeadline returns a context in which the failed requests to Argo CD are not retried if the next attempt
+// would start after the given deadline (eg: to cap the total time spent on the retries during a tool call)
+func WithRetryDeadline(ctx context.Context, deadline time.Time) context.Context {
+	return context.WithValue(ctx, retryDeadlineKey{}, deadline)
+}
+
+// retryDeadline returns the retry deadline of the given context, if any
+func retryDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(retryDeadlineKey{}).(time.Time)
+	return deadline, ok
+}
+
+// idempotentMethods the methods of the requests which can safely be sent again
+var idempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// transientStatusCodes the status codes of the responses to the requests which may succeed if sent again
+var transientStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryDelay returns the delay before the next attempt of the request with the given method, after the given response or error,
+// and `false` if the request should not be retried. The retries stop after `MaxRetries` retries.
+func (o RetryOptions) retryDelay(method string, retry int, resp *http.Response, err error) (time.Duration, bool) {
+	if retry > o.MaxRetries || !slices.Contains(idempotentMethods, method) {
+		return 0, false
+	}
+	switch {
+	case err != nil:
+		if !isConnectionReset(err) {
+			return 0, false
+		}
+	case !slices.Contains(transientStatusCodes, resp.StatusCode):
+		return 0, false
+	}
+	backoff := o.InitialBackoff << (retry - 1)
+	if backoff > o.MaxBackoff || backoff < o.InitialBackoff { // also handles overflows
+		backoff = o.MaxBackoff
+	}
+	// "equal jitter": between half and all of the backoff
+	delay := backoff/2 + rand.N(backoff/2+1) //nolint:gosec
+	if resp != nil {
+		if retryAfter, found := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); found {
+			if retryAfter > o.MaxBackoff {
+				return 0, false
+			}
+			delay = max(delay, retryAfter)
+		}
+	}
+	return delay, true
+}
+
+// isConnectionReset returns true if the given error was caused by a connection which was closed
+// unexpectedly (eg: by a proxy or a load balancer)
+func isConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseRetryAfter parses the value of the `Retry-After` header, which can be a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for the given delay, unless the context is done before
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
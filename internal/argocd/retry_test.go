@@ -0,0 +1,308 @@
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRetry(t *testing.T) {
+
+	// a minimal Argo CD server which fails the first requests with the given status (or by resetting the connection)
+	newServer := func(t *testing.T, failures int32, fail func(http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+		attempts := &atomic.Int32{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if attempts.Add(1) <= failures {
+				fail(w)
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}))
+		t.Cleanup(srv.Close)
+		return srv, attempts
+	}
+	withStatus := func(status int, headers ...string) func(http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			for i := 0; i+1 < len(headers); i += 2 {
+				w.Header().Set(headers[i], headers[i+1])
+			}
+			w.WriteHeader(status)
+		}
+	}
+	withConnectionReset := func(w http.ResponseWriter) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		_ = conn.(*net.TCPConn).SetLinger(0)
+		_ = conn.Close()
+	}
+	retryOptions := RetryOptions{
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	}
+
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			// given
+			srv, attempts := newServer(t, 2, withStatus(status))
+			logs := &bytes.Buffer{}
+			cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+				Retry:  retryOptions,
+				Logger: slog.New(slog.NewTextHandler(logs, nil)),
+			})
+			require.NoError(t, err)
+
+			// when
+			_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, int32(3), attempts.Load())
+			assert.Equal(t, 2, strings.Count(logs.String(), `msg="retrying request to Argo CD" method=GET path=api/v1/applications`))
+		})
+	}
+
+	t.Run("connection reset", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 1, withConnectionReset)
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Retry: retryOptions,
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+
+	t.Run("too many failures", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 10, withStatus(http.StatusBadGateway))
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Retry: retryOptions,
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		apiErr := &APIError{}
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+		assert.Equal(t, int32(4), attempts.Load())
+	})
+
+	t.Run("no retry for non-transient errors", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 1, withStatus(http.StatusInternalServerError))
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Retry: retryOptions,
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("no retry for non-idempotent requests", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 1, withStatus(http.StatusServiceUnavailable))
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Retry: retryOptions,
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.SyncApplication(context.Background(), ApplicationRef{Name: "example"}, SyncApplicationRequest{})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("no retry when disabled", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 1, withStatus(http.StatusServiceUnavailable))
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("no retry when Retry-After exceeds the max backoff", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 1, withStatus(http.StatusTooManyRequests, "Retry-After", "60"))
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Retry: retryOptions,
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("no retry after the retry deadline", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 1, withStatus(http.StatusServiceUnavailable))
+		logs := &bytes.Buffer{}
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Retry:  retryOptions,
+			Logger: slog.New(slog.NewTextHandler(logs, nil)),
+		})
+		require.NoError(t, err)
+		ctx := WithRetryDeadline(context.Background(), time.Now())
+
+		// when
+		_, err = cl.ListApplications(ctx, ListApplicationsOptions{})
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+		assert.Contains(t, logs.String(), `msg="not retrying request to Argo CD after the retry deadline" method=GET path=api/v1/applications`)
+	})
+
+	t.Run("retry before the retry deadline", func(t *testing.T) {
+		// given
+		srv, attempts := newServer(t, 1, withStatus(http.StatusServiceUnavailable))
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Retry: retryOptions,
+		})
+		require.NoError(t, err)
+		ctx := WithRetryDeadline(context.Background(), time.Now().Add(time.Minute))
+
+		// when
+		_, err = cl.ListApplications(ctx, ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		// given
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Timeout: 50 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		// when
+		_, err = cl.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.ErrorContains(t, err, "Client.Timeout exceeded")
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+
+	opts := RetryOptions{
+		MaxRetries:     10,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	t.Run("exponential backoff with jitter", func(t *testing.T) {
+		for retry, expected := range map[int]time.Duration{
+			1: 100 * time.Millisecond,
+			2: 200 * time.Millisecond,
+			3: 400 * time.Millisecond,
+			4: 800 * time.Millisecond,
+			5: time.Second, // max backoff
+			9: time.Second, // max backoff
+		} {
+			// when
+			delay, ok := opts.retryDelay(http.MethodGet, retry, &http.Response{StatusCode: http.StatusBadGateway}, nil)
+
+			// then
+			require.True(t, ok)
+			assert.GreaterOrEqual(t, delay, expected/2)
+			assert.LessOrEqual(t, delay, expected)
+		}
+	})
+
+	t.Run("Retry-After", func(t *testing.T) {
+		// when
+		delay, ok := opts.retryDelay(http.MethodGet, 1, &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Header: http.Header{
+				"Retry-After": []string{"1"},
+			},
+		}, nil)
+
+		// then
+		require.True(t, ok)
+		assert.Equal(t, time.Second, delay)
+	})
+
+	t.Run("max retries", func(t *testing.T) {
+		// when
+		_, ok := opts.retryDelay(http.MethodGet, 11, &http.Response{StatusCode: http.StatusBadGateway}, nil)
+
+		// then
+		assert.False(t, ok)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+
+	now := time.Date(2025, 8, 7, 13, 48, 0, 0, time.UTC)
+
+	testdata := []struct {
+		value         string
+		expected      time.Duration
+		expectedFound bool
+	}{
+		{value: "", expectedFound: false},
+		{value: "120", expected: 2 * time.Minute, expectedFound: true},
+		{value: "Thu, 07 Aug 2025 13:48:30 GMT", expected: 30 * time.Second, expectedFound: true},
+		{value: "Thu, 07 Aug 2025 13:47:00 GMT", expected: 0, expectedFound: true}, // in the past
+		{value: "soon", expectedFound: false},
+	}
+	for _, td := range testdata {
+		t.Run(td.value, func(t *testing.T) {
+			// when
+			delay, found := parseRetryAfter(td.value, now)
+
+			// then
+			assert.Equal(t, td.expectedFound, found)
+			assert.Equal(t, td.expected, delay)
+		})
+	}
+}
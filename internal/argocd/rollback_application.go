@@ -0,0 +1,79 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var RollbackApplicationTool = &mcp.Tool{
+	Name:         "rollbackApplication",
+	Description:  "rollback an Argo CD Application to a previous deployment (see the `applicationHistory` tool), and return the state of the resulting operation (or only `requested` if Argo CD did not start it in time). The Application must not have automated sync enabled",
+	InputSchema:  RollbackApplicationInputSchema,
+	OutputSchema: RollbackApplicationOutputSchema,
+}
+
+type RollbackApplicationInput struct {
+	Name         string `json:"name" jsonschema:"the name of the Argo CD Application to rollback"`
+	AppNamespace string `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance     string `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	ID           int64  `json:"id" jsonschema:"the ID of the deployment to rollback to, in the history of the Application"`
+	DryRun       bool   `json:"dryRun,omitempty" jsonschema:"preview the rollback without applying any change"`
+	Prune        bool   `json:"prune,omitempty" jsonschema:"delete the resources which are not defined in the deployment to rollback to"`
+}
+
+var RollbackApplicationInputSchema, _ = jsonschema.For[RollbackApplicationInput](&jsonschema.ForOptions{})
+
+type RollbackApplicationOutput struct {
+	OperationState OperationState `json:"operationState"`
+}
+
+var RollbackApplicationOutputSchema, _ = jsonschema.For[RollbackApplicationOutput](&jsonschema.ForOptions{})
+
+func RollbackApplicationToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[RollbackApplicationInput, RollbackApplicationOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in RollbackApplicationInput) (*mcp.CallToolResult, RollbackApplicationOutput, error) {
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, RollbackApplicationOutput{}, err
+		}
+		state, err := rollbackApplication(ctx, logger, cl, in)
+		if err != nil {
+			return nil, RollbackApplicationOutput{}, err
+		}
+		return nil, RollbackApplicationOutput{
+			OperationState: state,
+		}, nil
+	}
+}
+
+func rollbackApplication(ctx context.Context, logger *slog.Logger, cl Client, in RollbackApplicationInput) (OperationState, error) {
+	ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+	if err != nil {
+		return OperationState{}, err
+	}
+	ref, err = resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return OperationState{}, err
+	}
+	app, err := cl.RollbackApplication(ctx, ref, RollbackApplicationRequest{
+		ID:     in.ID,
+		DryRun: in.DryRun,
+		Prune:  in.Prune,
+	})
+	if err != nil {
+		return OperationState{}, fmt.Errorf("failed to rollback application '%s' to deployment %d: %w", ref, in.ID, err)
+	}
+	state := waitForOperation(ctx, logger, cl, ref, app)
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		stateStr, err := json.Marshal(state)
+		if err != nil {
+			logger.Error("failed to convert operation state to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "rollbackApplication", "app", ref.String(), "result", string(stateStr))
+	}
+	return state, nil
+}
@@ -0,0 +1,120 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackApplication(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	t.Run("known deployment", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		state, err := rollbackApplication(context.Background(), logger, cl, RollbackApplicationInput{
+			Name:   "example",
+			ID:     1,
+			DryRun: true,
+			Prune:  true,
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, RollbackApplicationRequest{
+			ID:     1,
+			DryRun: true,
+			Prune:  true,
+		}, cl.RollbackRequests["argocd/example"])
+		assert.False(t, state.Requested)
+		assert.Equal(t, "Succeeded", state.Phase)
+		assert.Equal(t, "3c1e9a4b5f6d7e8a9b0c1d2e3f4a5b6c7d8e9f01", state.Revision)
+		assert.True(t, state.DryRun)
+		require.NotEmpty(t, state.Resources)
+		assert.Equal(t, "configmap/example-config configured (dry run)", state.Resources[0].Message)
+	})
+
+	t.Run("operation not started", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{
+			OperationsNotStarted: true,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// when
+		state, err := rollbackApplication(ctx, logger, cl, RollbackApplicationInput{
+			Name: "example",
+			ID:   1,
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, OperationState{
+			Requested: true,
+			Message:   "the operation was requested, but not started by Argo CD yet",
+			Revision:  "3c1e9a4b5f6d7e8a9b0c1d2e3f4a5b6c7d8e9f01",
+		}, state)
+	})
+
+	t.Run("same name in several namespaces", func(t *testing.T) {
+
+		t.Run("ambiguous name", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			_, err := rollbackApplication(context.Background(), logger, cl, RollbackApplicationInput{
+				Name: "example-duplicate",
+				ID:   1,
+			})
+
+			// then
+			require.EqualError(t, err, "application name 'example-duplicate' is ambiguous, it matches team-a/example-duplicate, team-b/example-duplicate: specify the 'appNamespace' or use a namespace-qualified name")
+			assert.Empty(t, cl.RollbackRequests)
+		})
+
+		t.Run("namespace-qualified name", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			_, err := rollbackApplication(context.Background(), logger, cl, RollbackApplicationInput{
+				Name: "team-a/example-duplicate",
+				ID:   1,
+			})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, map[string]RollbackApplicationRequest{
+				"team-a/example-duplicate": {
+					ID: 1,
+				},
+			}, cl.RollbackRequests)
+		})
+	})
+
+	t.Run("unknown deployment", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := rollbackApplication(context.Background(), logger, cl, RollbackApplicationInput{
+			Name: "example",
+			ID:   3,
+		})
+
+		// then
+		require.EqualError(t, err, "failed to rollback application 'argocd/example' to deployment 3: unexpected Argo CD status 400: application 'example' does not have deployment id '3' in history")
+	})
+}
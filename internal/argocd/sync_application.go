@@ -0,0 +1,231 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var SyncApplicationTool = &mcp.Tool{
+	Name:         "syncApplication",
+	Description:  "sync an Argo CD Application (or some of its resources) with its target revision, and return the state of the resulting operation (or only `requested` if Argo CD did not start it in time)",
+	InputSchema:  SyncApplicationInputSchema,
+	OutputSchema: SyncApplicationOutputSchema,
+}
+
+type SyncApplicationInput struct {
+	Name         string         `json:"name" jsonschema:"the name of the Argo CD Application to sync"`
+	AppNamespace string         `json:"appNamespace,omitempty" jsonschema:"the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')"`
+	Instance     string         `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	Revision     string         `json:"revision,omitempty" jsonschema:"the revision to sync to (defaults to the target revision of the Application)"`
+	Prune        bool           `json:"prune,omitempty" jsonschema:"delete the resources which are no longer defined in Git"`
+	DryRun       bool           `json:"dryRun,omitempty" jsonschema:"preview the sync without applying any change"`
+	Force        bool           `json:"force,omitempty" jsonschema:"use a force apply (delete and re-create the resources when needed)"`
+	SyncOptions  []string       `json:"syncOptions,omitempty" jsonschema:"the sync options to use (eg: 'ServerSideApply=true', 'Replace=true')"`
+	Resources    []SyncResource `json:"resources,omitempty" jsonschema:"the resources to sync (defaults to all the resources of the Application)"`
+}
+
+// SyncResource a resource to sync, identified by its group, kind, namespace and name
+type SyncResource struct {
+	Group     string `json:"group,omitempty" jsonschema:"the API group of the resource (empty for the core group)"`
+	Kind      string `json:"kind" jsonschema:"the kind of the resource"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"the namespace of the resource (empty for cluster-scoped resources)"`
+	Name      string `json:"name" jsonschema:"the name of the resource"`
+}
+
+var SyncApplicationInputSchema, _ = jsonschema.For[SyncApplicationInput](&jsonschema.ForOptions{})
+
+type SyncApplicationOutput struct {
+	OperationState OperationState `json:"operationState"`
+}
+
+var SyncApplicationOutputSchema, _ = jsonschema.For[SyncApplicationOutput](&jsonschema.ForOptions{})
+
+func SyncApplicationToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[SyncApplicationInput, SyncApplicationOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in SyncApplicationInput) (*mcp.CallToolResult, SyncApplicationOutput, error) {
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, SyncApplicationOutput{}, err
+		}
+		state, err := syncApplication(ctx, logger, cl, in)
+		if err != nil {
+			return nil, SyncApplicationOutput{}, err
+		}
+		return nil, SyncApplicationOutput{
+			OperationState: state,
+		}, nil
+	}
+}
+
+func syncApplication(ctx context.Context, logger *slog.Logger, cl Client, in SyncApplicationInput) (OperationState, error) {
+	req := SyncApplicationRequest{
+		Revision: in.Revision,
+		DryRun:   in.DryRun,
+		Prune:    in.Prune,
+	}
+	if in.Force {
+		// same strategy as with `argocd app sync --force`
+		req.Strategy = &argocdv3.SyncStrategy{
+			Hook: &argocdv3.SyncStrategyHook{
+				SyncStrategyApply: argocdv3.SyncStrategyApply{
+					Force: true,
+				},
+			},
+		}
+	}
+	if len(in.SyncOptions) > 0 {
+		req.SyncOptions = &SyncOptions{
+			Items: in.SyncOptions,
+		}
+	}
+	for _, r := range in.Resources {
+		req.Resources = append(req.Resources, argocdv3.SyncOperationResource{
+			Group:     r.Group,
+			Kind:      r.Kind,
+			Namespace: r.Namespace,
+			Name:      r.Name,
+		})
+	}
+	ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+	if err != nil {
+		return OperationState{}, err
+	}
+	ref, err = resolveApplicationRef(ctx, cl, ref)
+	if err != nil {
+		return OperationState{}, err
+	}
+	app, err := cl.SyncApplication(ctx, ref, req)
+	if err != nil {
+		return OperationState{}, fmt.Errorf("failed to sync application '%s': %w", ref, err)
+	}
+	state := waitForOperation(ctx, logger, cl, ref, app)
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		stateStr, err := json.Marshal(state)
+		if err != nil {
+			logger.Error("failed to convert operation state to text", "error", err.Error())
+		}
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "syncApplication", "app", ref.String(), "result", string(stateStr))
+	}
+	return state, nil
+}
+
+// OperationState the state of the last (or ongoing) operation on an application
+type OperationState struct {
+	// Requested is set when the operation was requested but not started by Argo CD yet,
+	// in which case the phase, the timestamps and the resources are not set
+	Requested  bool             `json:"requested,omitempty"`
+	Phase      string           `json:"phase,omitempty"`
+	Message    string           `json:"message,omitempty"`
+	Revision   string           `json:"revision,omitempty"`
+	DryRun     bool             `json:"dryRun,omitempty"`
+	StartedAt  string           `json:"startedAt,omitempty"`
+	FinishedAt string           `json:"finishedAt,omitempty"`
+	Resources  []ResourceResult `json:"resources,omitempty"`
+}
+
+// ResourceResult the result of the operation on a single resource
+type ResourceResult struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Status    string `json:"status,omitempty"`
+	Message   string `json:"message,omitempty"`
+	SyncPhase string `json:"syncPhase,omitempty"`
+}
+
+const (
+	// operationTimeout the maximum time to wait for Argo CD to start and complete a requested operation
+	operationTimeout = 10 * time.Second
+	// operationPollInterval the delay between 2 checks of the state of a requested operation
+	operationPollInterval = 500 * time.Millisecond
+)
+
+// waitForOperation polls the given application until Argo CD started and completed the operation which was just requested,
+// and returns the state of this operation (which may still be running after the `operationTimeout`).
+// The given application is the one returned by Argo CD when the operation was requested: its `operation` is set,
+// but its `status.operationState` is still the one of the previous operation (if any), until Argo CD starts the new one.
+// If Argo CD did not start the operation in time, the returned state is only `requested`.
+func waitForOperation(ctx context.Context, logger *slog.Logger, cl Client, ref ApplicationRef, requested *argocdv3.Application) OperationState {
+	state := newRequestedOperationState(requested)
+	previous := requested.Status.OperationState
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+	for {
+		// bypass the cache, which does not have the latest state of the application
+		app, err := cl.GetApplication(WithoutCache(ctx), ref)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.WarnContext(ctx, "failed to get the state of the requested operation", "app", ref.String(), "error", err.Error())
+			}
+			return state
+		}
+		if s := app.Status.OperationState; s != nil && (previous == nil || !s.StartedAt.Equal(&previous.StartedAt)) {
+			state = newOperationState(s)
+			if s.Phase.Completed() {
+				return state
+			}
+		}
+		if sleep(ctx, operationPollInterval) != nil {
+			return state
+		}
+	}
+}
+
+// newRequestedOperationState returns the state of the operation which was requested on the given application,
+// but not started by Argo CD yet
+func newRequestedOperationState(app *argocdv3.Application) OperationState {
+	state := OperationState{
+		Requested: true,
+		Message:   "the operation was requested, but not started by Argo CD yet",
+	}
+	if op := app.Operation; op != nil && op.Sync != nil {
+		state.Revision = op.Sync.Revision
+		state.DryRun = op.Sync.DryRun
+	}
+	return state
+}
+
+// newOperationState returns the given state of an operation started by Argo CD
+func newOperationState(s *argocdv3.OperationState) OperationState {
+	state := OperationState{
+		Phase:      string(s.Phase),
+		Message:    s.Message,
+		StartedAt:  formatTime(&s.StartedAt),
+		FinishedAt: formatTime(s.FinishedAt),
+	}
+	if s.Operation.Sync != nil {
+		state.DryRun = s.Operation.Sync.DryRun
+	}
+	if r := s.SyncResult; r != nil {
+		state.Revision = r.Revision
+		for _, res := range r.Resources {
+			state.Resources = append(state.Resources, ResourceResult{
+				Group:     res.Group,
+				Version:   res.Version,
+				Kind:      res.Kind,
+				Namespace: res.Namespace,
+				Name:      res.Name,
+				Status:    string(res.Status),
+				Message:   res.Message,
+				SyncPhase: string(res.SyncPhase),
+			})
+		}
+	}
+	return state
+}
+
+func formatTime(t *metav1.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,187 @@
+package argocd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncApplication(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	t.Run("selected resources", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		state, err := syncApplication(context.Background(), logger, cl, SyncApplicationInput{
+			Name:        "example",
+			Revision:    "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+			Prune:       true,
+			DryRun:      true,
+			Force:       true,
+			SyncOptions: []string{"ServerSideApply=true"},
+			Resources: []SyncResource{
+				{
+					Group:     "external-secrets.io",
+					Kind:      "ExternalSecret",
+					Namespace: "example-ns",
+					Name:      "example-secret",
+				},
+			},
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, SyncApplicationRequest{
+			Revision: "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+			DryRun:   true,
+			Prune:    true,
+			Strategy: &argocdv3.SyncStrategy{
+				Hook: &argocdv3.SyncStrategyHook{
+					SyncStrategyApply: argocdv3.SyncStrategyApply{
+						Force: true,
+					},
+				},
+			},
+			Resources: []argocdv3.SyncOperationResource{
+				{
+					Group:     "external-secrets.io",
+					Kind:      "ExternalSecret",
+					Namespace: "example-ns",
+					Name:      "example-secret",
+				},
+			},
+			SyncOptions: &SyncOptions{
+				Items: []string{"ServerSideApply=true"},
+			},
+		}, cl.SyncRequests["argocd/example"])
+		// the results of the dry run are returned
+		assert.False(t, state.Requested)
+		assert.Equal(t, "Succeeded", state.Phase)
+		assert.Equal(t, "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04", state.Revision)
+		assert.True(t, state.DryRun)
+		assert.NotEmpty(t, state.StartedAt)
+		require.Len(t, state.Resources, 1)
+		assert.Equal(t, "externalsecret/example-secret configured (dry run)", state.Resources[0].Message)
+	})
+
+	t.Run("operation not started", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{
+			OperationsNotStarted: true,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// when
+		state, err := syncApplication(ctx, logger, cl, SyncApplicationInput{
+			Name:     "example",
+			Revision: "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+			DryRun:   true,
+		})
+
+		// then the state of the previous operation is not returned
+		require.NoError(t, err)
+		assert.Equal(t, OperationState{
+			Requested: true,
+			Message:   "the operation was requested, but not started by Argo CD yet",
+			Revision:  "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+			DryRun:    true,
+		}, state)
+	})
+
+	t.Run("all resources", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := syncApplication(context.Background(), logger, cl, SyncApplicationInput{
+			Name: "example",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, SyncApplicationRequest{}, cl.SyncRequests["argocd/example"])
+	})
+
+	t.Run("same name in several namespaces", func(t *testing.T) {
+
+		t.Run("ambiguous name", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			_, err := syncApplication(context.Background(), logger, cl, SyncApplicationInput{
+				Name: "example-duplicate",
+			})
+
+			// then
+			require.EqualError(t, err, "application name 'example-duplicate' is ambiguous, it matches team-a/example-duplicate, team-b/example-duplicate: specify the 'appNamespace' or use a namespace-qualified name")
+			assert.Empty(t, cl.SyncRequests)
+		})
+
+		t.Run("name and namespace", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			_, err := syncApplication(context.Background(), logger, cl, SyncApplicationInput{
+				Name:         "example-duplicate",
+				AppNamespace: "team-b",
+			})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, map[string]SyncApplicationRequest{
+				"team-b/example-duplicate": {},
+			}, cl.SyncRequests)
+		})
+	})
+
+	t.Run("argocd error", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := syncApplication(context.Background(), logger, cl, SyncApplicationInput{
+			Name: "example-error",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to get application 'example-error' from Argo CD")
+	})
+}
+
+func TestNewOperationState(t *testing.T) {
+	// given
+	app, err := (&FakeArgoCDClient{}).GetApplication(context.Background(), ApplicationRef{Name: "example"})
+	require.NoError(t, err)
+
+	// when
+	state := newOperationState(app.Status.OperationState)
+
+	// then
+	assert.Equal(t, "Running", state.Phase)
+	assert.Equal(t, "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04", state.Revision)
+	assert.Equal(t, "2025-08-07T13:48:00Z", state.StartedAt)
+	assert.Equal(t, "2025-08-07T13:48:06Z", state.FinishedAt)
+	require.NotEmpty(t, state.Resources)
+	assert.Equal(t, ResourceResult{
+		Version:   "v1",
+		Kind:      "Namespace",
+		Name:      "example-ns",
+		Status:    "Synced",
+		Message:   "namespace/example-ns created",
+		SyncPhase: "PreSync",
+	}, state.Resources[0])
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
+	"slices"
+	"strings"
 
 	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/argoproj/gitops-engine/pkg/health"
@@ -23,16 +23,32 @@ var UnhealthyResourcesPrompt = &mcp.Prompt{
 			Description: "the name of the application to get details of",
 			Required:    true,
 		},
+		{
+			Name:        "appNamespace",
+			Description: "the namespace of the application (optional, the name can also be qualified as 'namespace/name')",
+		},
+		{
+			Name:        "instance",
+			Description: "the name of the Argo CD instance to query (optional, defaults to the first configured instance)",
+		},
 	},
 }
 
-func UnhealthyApplicationResourcesPromptHandle(logger *slog.Logger, cl Client) func(context.Context, *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+func UnhealthyApplicationResourcesPromptHandle(logger *slog.Logger, instances *Instances) func(context.Context, *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
 	return func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
 		app, ok := req.Params.Arguments["name"]
 		if !ok {
 			return nil, fmt.Errorf("'name' not found in arguments or not a string")
 		}
-		unhealthyResources, err := listUnhealthyApplicationResources(ctx, logger, cl, app)
+		cl, err := instances.Client(req.Params.Arguments["instance"])
+		if err != nil {
+			return nil, err
+		}
+		ref, err := NewApplicationRef(app, req.Params.Arguments["appNamespace"])
+		if err != nil {
+			return nil, err
+		}
+		unhealthyResources, err := listUnhealthyApplicationResources(ctx, logger, cl, ref)
 		if err != nil {
 			return nil, err
 		}
@@ -68,6 +84,18 @@ var UnhealthyApplicationResourcesTool = &mcp.Tool{
 				Type:        "string",
 				Description: "the name of the Argo CD Application to get details of",
 			},
+			"appNamespace": {
+				Type:        "string",
+				Description: "the namespace of the Argo CD Application (optional, the name can also be qualified as 'namespace/name')",
+			},
+			"instance": {
+				Type:        "string",
+				Description: "the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)",
+			},
+			"fresh": {
+				Type:        "boolean",
+				Description: "bypass the cache and fetch the latest state from Argo CD (optional, only needed right after a change which was not made with this server)",
+			},
 		},
 		Required: []string{"name"},
 	},
@@ -77,14 +105,28 @@ var UnhealthyApplicationResourcesTool = &mcp.Tool{
 var UnhealthyApplicationResourcesOutputSchema, _ = jsonschema.For[UnhealthyApplicationResourcesOutput](&jsonschema.ForOptions{})
 
 type UnhealthyApplicationResourcesInput struct {
-	Name string `json:"name"`
+	Name         string `json:"name"`
+	AppNamespace string `json:"appNamespace,omitempty"`
+	Instance     string `json:"instance,omitempty"`
+	Fresh        bool   `json:"fresh,omitempty"`
 }
 
 type UnhealthyApplicationResourcesOutput UnhealthyResources
 
-func UnhealthyApplicationResourcesToolHandle(logger *slog.Logger, cl Client) mcp.ToolHandlerFor[UnhealthyApplicationResourcesInput, UnhealthyApplicationResourcesOutput] {
+func UnhealthyApplicationResourcesToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[UnhealthyApplicationResourcesInput, UnhealthyApplicationResourcesOutput] {
 	return func(ctx context.Context, _ *mcp.CallToolRequest, in UnhealthyApplicationResourcesInput) (*mcp.CallToolResult, UnhealthyApplicationResourcesOutput, error) {
-		unhealthyResources, err := listUnhealthyApplicationResources(ctx, logger, cl, in.Name)
+		if in.Fresh {
+			ctx = WithoutCache(ctx)
+		}
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, UnhealthyApplicationResourcesOutput{}, err
+		}
+		ref, err := NewApplicationRef(in.Name, in.AppNamespace)
+		if err != nil {
+			return nil, UnhealthyApplicationResourcesOutput{}, err
+		}
+		unhealthyResources, err := listUnhealthyApplicationResources(ctx, logger, cl, ref)
 		if err != nil {
 			return nil, UnhealthyApplicationResourcesOutput{}, err
 		}
@@ -92,27 +134,11 @@ func UnhealthyApplicationResourcesToolHandle(logger *slog.Logger, cl Client) mcp
 	}
 }
 
-func listUnhealthyApplicationResources(ctx context.Context, logger *slog.Logger, cl Client, name string) (UnhealthyResources, error) {
-	resp, err := cl.GetWithContext(ctx, fmt.Sprintf("api/v1/applications?name=%s", name)) // no heading `/` in the path
-	if err != nil {
-		return UnhealthyResources{}, fmt.Errorf("failed to get application '%s' from Argo CD: %w", name, err)
-	}
-	body, err := io.ReadAll(resp.Body)
-	defer resp.Body.Close()
+func listUnhealthyApplicationResources(ctx context.Context, logger *slog.Logger, cl Client, ref ApplicationRef) (UnhealthyResources, error) {
+	app, err := getApplication(ctx, cl, ref, "status.resources")
 	if err != nil {
-		return UnhealthyResources{}, fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return UnhealthyResources{}, fmt.Errorf("unexpected Argo CD status %d for application '%s': %s", resp.StatusCode, name, string(body))
-	}
-	apps := &argocdv3.ApplicationList{}
-	if err = json.Unmarshal(body, apps); err != nil {
-		return UnhealthyResources{}, fmt.Errorf("failed to unmarshal application list: %w", err)
+		return UnhealthyResources{}, err
 	}
-	if len(apps.Items) == 0 {
-		return UnhealthyResources{}, fmt.Errorf("no application found with name %s", name)
-	}
-	app := apps.Items[0]
 	// retain unhealthy resources from the name status
 	unhealthyResources := []argocdv3.ResourceStatus{}
 	for _, resource := range app.Status.Resources {
@@ -126,13 +152,94 @@ func listUnhealthyApplicationResources(ctx context.Context, logger *slog.Logger,
 		if err != nil {
 			logger.Error("failed to convert unhealthy resources to text", "error", err.Error())
 		}
-		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "unhealthyApplicationResources", "app", name, "result", string(unhealthyResourcesStr))
+		logger.DebugContext(ctx, "returned 'tools/call' response", "tool", "unhealthyApplicationResources", "app", ref.String(), "result", string(unhealthyResourcesStr))
 	}
 	return UnhealthyResources{
 		Resources: unhealthyResources,
 	}, nil
 }
 
+// getApplication returns the referenced application, with its name, namespace and the given fields only (eg: `status.resources`).
+// If the reference has no namespace, then the application name must be unique among all the namespaces that Argo CD watches.
+func getApplication(ctx context.Context, cl Client, ref ApplicationRef, fields ...string) (*argocdv3.Application, error) {
+	apps, err := cl.ListApplications(ctx, ListApplicationsOptions{
+		Name:         ref.Name,
+		AppNamespace: ref.Namespace,
+		Fields:       applicationFields(fields...),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get application '%s' from Argo CD: %w", ref, err)
+	}
+	switch len(apps.Items) {
+	case 0:
+		return nil, fmt.Errorf("no application found with name %s", ref)
+	case 1:
+		return &apps.Items[0], nil
+	default:
+		names := make([]string, 0, len(apps.Items))
+		for _, app := range apps.Items {
+			names = append(names, qualifiedName(app))
+		}
+		return nil, fmt.Errorf("application name '%s' is ambiguous, it matches %s: specify the 'appNamespace' or use a namespace-qualified name", ref, strings.Join(names, ", "))
+	}
+}
+
+// resolveApplicationRef returns the reference of the application with the given name, qualified with its namespace,
+// so that an ambiguous name is rejected rather than silently resolved by Argo CD in its own namespace
+func resolveApplicationRef(ctx context.Context, cl Client, ref ApplicationRef) (ApplicationRef, error) {
+	app, err := getApplication(ctx, cl, ref)
+	if err != nil {
+		return ApplicationRef{}, err
+	}
+	return ApplicationRef{
+		Name:      app.Name,
+		Namespace: app.Namespace,
+	}, nil
+}
+
+// projectableApplicationFields the fields of the applications which Argo CD can project when listing the applications
+// (see `appFields` in Argo CD's `server/application/forwarder_overwrite.go`). The other fields are silently dropped.
+var projectableApplicationFields = []string{
+	"metadata.name",
+	"metadata.namespace",
+	"metadata.annotations",
+	"metadata.labels",
+	"metadata.creationTimestamp",
+	"metadata.deletionTimestamp",
+	"spec",
+	"status.sync.status",
+	"status.health",
+	"status.summary",
+	"status.operationState.startedAt",
+	"status.operationState.finishedAt",
+	"status.resources",
+	"operation.sync",
+	"status.operationState.phase",
+	"status.operationState.operation.sync",
+}
+
+// applicationFields returns the paths of the given fields of the applications in a list (eg: `items.status.health`
+// for `status.health`), along with the name and namespace of the applications and the resource version of the list.
+// Panics if one of the fields cannot be projected by Argo CD.
+func applicationFields(fields ...string) []string {
+	paths := []string{"metadata.resourceVersion", "items.metadata.name", "items.metadata.namespace"}
+	for _, f := range fields {
+		if !slices.Contains(projectableApplicationFields, f) {
+			panic(fmt.Sprintf("field '%s' cannot be projected by Argo CD", f))
+		}
+		paths = append(paths, "items."+f)
+	}
+	return paths
+}
+
+// qualifiedName returns the namespace-qualified name of the given application (`namespace/name`)
+func qualifiedName(app argocdv3.Application) string {
+	return ApplicationRef{
+		Name:      app.Name,
+		Namespace: app.Namespace,
+	}.String()
+}
+
 // a wrapper, because `runtime.DefaultUnstructuredConverter.ToUnstructured`:
 // - requires a pointer to a struct
 // - does not support anonymous structs
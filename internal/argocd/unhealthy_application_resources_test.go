@@ -21,7 +21,7 @@ func TestListUnhealthyApplicationResources(t *testing.T) {
 		}))
 
 		// when
-		unhealthyResources, err := listUnhealthyApplicationResources(context.Background(), logger, cl, "example")
+		unhealthyResources, err := listUnhealthyApplicationResources(context.Background(), logger, cl, ApplicationRef{Name: "example"})
 
 		// then
 		require.NoError(t, err)
@@ -60,4 +60,60 @@ func TestListUnhealthyApplicationResources(t *testing.T) {
 			},
 		}, unhealthyResources)
 	})
+	t.Run("application in multiple namespaces", func(t *testing.T) {
+
+		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		}))
+
+		t.Run("ambiguous name", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			_, err := listUnhealthyApplicationResources(context.Background(), logger, cl, ApplicationRef{Name: "example-duplicate"})
+
+			// then
+			require.EqualError(t, err, "application name 'example-duplicate' is ambiguous, it matches team-a/example-duplicate, team-b/example-duplicate: specify the 'appNamespace' or use a namespace-qualified name")
+		})
+
+		t.Run("with namespace", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+
+			// when
+			unhealthyResources, err := listUnhealthyApplicationResources(context.Background(), logger, cl, ApplicationRef{Name: "example-duplicate", Namespace: "team-b"})
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, unhealthyResources.Resources, 3)
+		})
+
+		t.Run("with qualified name", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+			ref, err := NewApplicationRef("team-a/example-duplicate", "")
+			require.NoError(t, err)
+
+			// when
+			unhealthyResources, err := listUnhealthyApplicationResources(context.Background(), logger, cl, ref)
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, unhealthyResources.Resources, 3)
+		})
+
+		t.Run("unknown namespace", func(t *testing.T) {
+			// given
+			cl := &FakeArgoCDClient{}
+			ref, err := NewApplicationRef("team-c/example-duplicate", "")
+			require.NoError(t, err)
+
+			// when
+			_, err = listUnhealthyApplicationResources(context.Background(), logger, cl, ref)
+
+			// then
+			require.EqualError(t, err, "no application found with name team-c/example-duplicate")
+		})
+	})
 }
@@ -3,26 +3,40 @@ package argocd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
+	"slices"
+	"sync"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/sync/errgroup"
 
 	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	argocdhealth "github.com/argoproj/gitops-engine/pkg/health"
+	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
 )
 
 var UnhealthyApplicationsTool = &mcp.Tool{
 	Name:         "unhealthyApplications",
-	Description:  "list the unhealthy ('degraded' and 'progressing') Applications in Argo CD",
+	Description:  "list the unhealthy Applications in Argo CD, grouped by health status (eg: 'degraded' and 'progressing'), sync status ('outOfSync' and 'unknown'), error conditions and failed operations. Use `verbose` to also get the reason why each Application is unhealthy",
 	InputSchema:  UnhealthyApplicationsInputSchema,
 	OutputSchema: UnhealthyApplicationsOutputSchema,
 }
 
 type UnhealthyApplicationsInput struct {
+	Projects             []string `json:"projects,omitempty" jsonschema:"only return the Applications of these projects"`
+	Selector             string   `json:"selector,omitempty" jsonschema:"only return the Applications matching this label selector (eg: 'team=a,env!=dev')"`
+	RepoURL              string   `json:"repoURL,omitempty" jsonschema:"only return the Applications whose source is this repository"`
+	AppNamespace         string   `json:"appNamespace,omitempty" jsonschema:"only return the Applications in this namespace"`
+	DestinationServer    string   `json:"destinationServer,omitempty" jsonschema:"only return the Applications deployed on the cluster with this URL"`
+	DestinationName      string   `json:"destinationName,omitempty" jsonschema:"only return the Applications deployed on the cluster with this name"`
+	DestinationNamespace string   `json:"destinationNamespace,omitempty" jsonschema:"only return the Applications deployed in this namespace"`
+	Instance             string   `json:"instance,omitempty" jsonschema:"the name of the Argo CD instance to query (optional, defaults to the first instance returned by the listInstances tool)"`
+	Fresh                bool     `json:"fresh,omitempty" jsonschema:"bypass the cache and fetch the latest state from Argo CD (optional, only needed right after a change which was not made with this server)"`
+	AllInstances         bool     `json:"allInstances,omitempty" jsonschema:"query all the Argo CD instances, in which case the names of the Applications are prefixed with the name of their instance (eg: 'prod:argocd/my-app'). Cannot be combined with the 'instance'"`
+	Verbose              bool     `json:"verbose,omitempty" jsonschema:"also return the details of each unhealthy Application (project, destination, health message, sync status and last operation)"`
 }
 
 var UnhealthyApplicationsInputSchema, _ = jsonschema.For[UnhealthyApplicationsInput](&jsonschema.ForOptions{})
@@ -31,9 +45,23 @@ type UnhealthyApplicationsOutput UnhealthyApplications
 
 var UnhealthyApplicationsOutputSchema, _ = jsonschema.For[UnhealthyApplicationsOutput](&jsonschema.ForOptions{})
 
-func UnhealthyApplicationsToolHandle(logger *slog.Logger, cl Client) mcp.ToolHandlerFor[UnhealthyApplicationsInput, UnhealthyApplicationsOutput] {
-	return func(ctx context.Context, _ *mcp.CallToolRequest, _ UnhealthyApplicationsInput) (*mcp.CallToolResult, UnhealthyApplicationsOutput, error) {
-		apps, err := listUnhealthyApplications(ctx, logger, cl)
+func UnhealthyApplicationsToolHandle(logger *slog.Logger, instances *Instances) mcp.ToolHandlerFor[UnhealthyApplicationsInput, UnhealthyApplicationsOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in UnhealthyApplicationsInput) (*mcp.CallToolResult, UnhealthyApplicationsOutput, error) {
+		if in.Fresh {
+			ctx = WithoutCache(ctx)
+		}
+		if in.AllInstances {
+			apps, err := listUnhealthyApplicationsInAllInstances(ctx, logger, instances, in)
+			if err != nil {
+				return nil, UnhealthyApplicationsOutput{}, err
+			}
+			return nil, UnhealthyApplicationsOutput(apps), nil
+		}
+		cl, err := instances.Client(in.Instance)
+		if err != nil {
+			return nil, UnhealthyApplicationsOutput{}, err
+		}
+		apps, err := listUnhealthyApplications(ctx, logger, cl, in)
 		if err != nil {
 			return nil, UnhealthyApplicationsOutput{}, err
 		}
@@ -41,56 +69,169 @@ func UnhealthyApplicationsToolHandle(logger *slog.Logger, cl Client) mcp.ToolHan
 	}
 }
 
+// UnhealthyApplications the namespace-qualified names (`namespace/name`) of the unhealthy applications, grouped by health status, sync status,
+// error conditions and failed operations. An application can be in multiple groups (eg: `Degraded` and `OutOfSync`).
+// When querying all the instances, the names are prefixed with the name of the instance (`instance:namespace/name`).
 type UnhealthyApplications struct {
+	Health     HealthBuckets    `json:"health,omitzero"`
+	Sync       SyncBuckets      `json:"sync,omitzero"`
+	Conditions ConditionBuckets `json:"conditions,omitzero"`
+	// OperationFailed the applications whose last operation (eg: sync) failed
+	OperationFailed []string `json:"operationFailed,omitempty"`
+	// Applications the details of the unhealthy applications (in verbose mode only)
+	Applications []ApplicationSummary `json:"applications,omitempty"`
+	// Errors the errors of the instances which could not be queried, indexed by instance name (when querying all the instances)
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// HealthBuckets the names of the applications grouped by their (unhealthy) health status
+type HealthBuckets struct {
 	Degraded    []string `json:"degraded,omitempty"`
 	Progressing []string `json:"progressing,omitempty"`
 	Missing     []string `json:"missing,omitempty"`
 	Unknown     []string `json:"unknown,omitempty"`
 	Suspended   []string `json:"suspended,omitempty"`
-	OutOfSync   []string `json:"outOfSync,omitempty"`
 }
 
-// returns the name of the applications grouped by their health status
-func listUnhealthyApplications(ctx context.Context, logger *slog.Logger, cl Client) (UnhealthyApplications, error) {
-	resp, err := cl.GetWithContext(ctx, "api/v1/applications")
-	if err != nil {
-		return UnhealthyApplications{}, err
-	}
-	body, err := io.ReadAll(resp.Body)
-	defer resp.Body.Close()
+// SyncBuckets the names of the applications grouped by their (unsynced) sync status
+type SyncBuckets struct {
+	OutOfSync []string `json:"outOfSync,omitempty"`
+	Unknown   []string `json:"unknown,omitempty"`
+}
+
+// ConditionBuckets the names of the applications grouped by the type of their error conditions
+type ConditionBuckets struct {
+	ComparisonError  []string `json:"comparisonError,omitempty"`
+	SyncError        []string `json:"syncError,omitempty"`
+	InvalidSpecError []string `json:"invalidSpecError,omitempty"`
+	// OtherError the applications with other error conditions (eg: `DeletionError`)
+	OtherError []string `json:"otherError,omitempty"`
+}
+
+// ApplicationSummary the details of an application
+type ApplicationSummary struct {
+	// Instance the name of the Argo CD instance of the application (when querying all the instances)
+	Instance      string             `json:"instance,omitempty"`
+	Name          string             `json:"name"`
+	Namespace     string             `json:"namespace,omitempty"`
+	Project       string             `json:"project,omitempty"`
+	Destination   DestinationSummary `json:"destination"`
+	Health        HealthSummary      `json:"health"`
+	Sync          SyncSummary        `json:"sync"`
+	LastOperation *OperationSummary  `json:"lastOperation,omitempty"`
+	// Conditions the error conditions, as `Type: message`
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+// DestinationSummary the cluster and namespace in which an application is deployed
+type DestinationSummary struct {
+	Server    string `json:"server,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// OperationSummary the outcome of the last operation (eg: sync) of an application
+type OperationSummary struct {
+	Phase      string `json:"phase"`
+	Message    string `json:"message,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+}
+
+// unhealthyApplicationsFields the fields of the applications needed to find the unhealthy applications.
+// The other fields (eg: the conditions) cannot be projected by Argo CD, so they are read from the full
+// applications, which are fetched for the unhealthy applications only
+var unhealthyApplicationsFields = applicationFields(
+	"spec",
+	"status.health",
+	"status.sync.status",
+	"status.operationState.phase",
+	"status.operationState.finishedAt",
+)
+
+// maxConcurrentApplicationGets the maximum number of applications fetched concurrently from Argo CD
+const maxConcurrentApplicationGets = 10
+
+// returns the name of the applications grouped by their health status, sync status, error conditions
+// and failed operations, along with their details if `verbose` is set.
+// Note: the conditions are only checked for the applications which are not healthy, not synced or whose
+// last operation failed, since the conditions are not returned by Argo CD when listing the applications
+func listUnhealthyApplications(ctx context.Context, logger *slog.Logger, cl Client, in UnhealthyApplicationsInput) (UnhealthyApplications, error) {
+	// filter on the project, labels, repository and namespace on the Argo CD side,
+	// and only fetch the fields needed to find the unhealthy applications (ie: not the resources nor the history)
+	apps, err := cl.ListApplications(ctx, ListApplicationsOptions{
+		Projects:     in.Projects,
+		Selector:     in.Selector,
+		Repo:         in.RepoURL,
+		AppNamespace: in.AppNamespace,
+		Fields:       unhealthyApplicationsFields,
+	})
 	if err != nil {
-		return UnhealthyApplications{}, fmt.Errorf("failed to read HTTP response body: %w", err)
+		return UnhealthyApplications{}, fmt.Errorf("failed to list applications from Argo CD: %w", err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return UnhealthyApplications{}, fmt.Errorf("unexpected Argo CD status %d: %s", resp.StatusCode, string(body))
-	}
-	apps := &argocdv3.ApplicationList{}
-	if err = json.Unmarshal(body, apps); err != nil {
-		return UnhealthyApplications{}, fmt.Errorf("failed to unmarshal application list: %w", err)
+	candidates := []argocdv3.Application{}
+	for _, app := range apps.Items {
+		// filter on the destination on the client side, as it is not supported by Argo CD
+		if (in.DestinationServer != "" && app.Spec.Destination.Server != in.DestinationServer) ||
+			(in.DestinationName != "" && app.Spec.Destination.Name != in.DestinationName) ||
+			(in.DestinationNamespace != "" && app.Spec.Destination.Namespace != in.DestinationNamespace) {
+			continue
+		}
+		if maybeUnhealthy(app) {
+			candidates = append(candidates, app)
+		}
 	}
-	unhealthyApps := UnhealthyApplications{
-		Degraded:    []string{},
-		Progressing: []string{},
-		OutOfSync:   []string{},
+	// fetch the full applications to get their conditions, revision and last operation message
+	candidates, err = getApplications(ctx, cl, candidates)
+	if err != nil {
+		return UnhealthyApplications{}, err
 	}
-	for _, app := range apps.Items {
+	unhealthyApps := UnhealthyApplications{}
+	for _, app := range candidates {
+		unhealthy := true
 		switch app.Status.Health.Status {
 		case argocdhealth.HealthStatusDegraded:
-			unhealthyApps.Degraded = append(unhealthyApps.Degraded, app.Name)
+			unhealthyApps.Health.Degraded = append(unhealthyApps.Health.Degraded, qualifiedName(app))
 		case argocdhealth.HealthStatusProgressing:
-			unhealthyApps.Progressing = append(unhealthyApps.Progressing, app.Name)
+			unhealthyApps.Health.Progressing = append(unhealthyApps.Health.Progressing, qualifiedName(app))
 		case argocdhealth.HealthStatusMissing:
-			unhealthyApps.Missing = append(unhealthyApps.Missing, app.Name)
+			unhealthyApps.Health.Missing = append(unhealthyApps.Health.Missing, qualifiedName(app))
 		case argocdhealth.HealthStatusUnknown:
-			unhealthyApps.Unknown = append(unhealthyApps.Unknown, app.Name)
+			unhealthyApps.Health.Unknown = append(unhealthyApps.Health.Unknown, qualifiedName(app))
 		case argocdhealth.HealthStatusSuspended:
-			unhealthyApps.Suspended = append(unhealthyApps.Suspended, app.Name)
-		case argocdhealth.HealthStatusHealthy:
-			if app.Status.Sync.Status == argocdv3.SyncStatusCodeOutOfSync {
-				unhealthyApps.OutOfSync = append(unhealthyApps.OutOfSync, app.Name)
-			}
+			unhealthyApps.Health.Suspended = append(unhealthyApps.Health.Suspended, qualifiedName(app))
+		default:
+			// skip healthy apps
+			unhealthy = false
+		}
+		switch app.Status.Sync.Status {
+		case argocdv3.SyncStatusCodeOutOfSync:
+			unhealthyApps.Sync.OutOfSync = append(unhealthyApps.Sync.OutOfSync, qualifiedName(app))
+			unhealthy = true
+		case argocdv3.SyncStatusCodeUnknown:
+			unhealthyApps.Sync.Unknown = append(unhealthyApps.Sync.Unknown, qualifiedName(app))
+			unhealthy = true
 		default:
-			// skip healthy/synced apps
+			// skip synced apps
+		}
+		for _, t := range errorConditionTypes(app) {
+			switch t {
+			case argocdv3.ApplicationConditionComparisonError:
+				unhealthyApps.Conditions.ComparisonError = append(unhealthyApps.Conditions.ComparisonError, qualifiedName(app))
+			case argocdv3.ApplicationConditionSyncError:
+				unhealthyApps.Conditions.SyncError = append(unhealthyApps.Conditions.SyncError, qualifiedName(app))
+			case argocdv3.ApplicationConditionInvalidSpecError:
+				unhealthyApps.Conditions.InvalidSpecError = append(unhealthyApps.Conditions.InvalidSpecError, qualifiedName(app))
+			default:
+				unhealthyApps.Conditions.OtherError = append(unhealthyApps.Conditions.OtherError, qualifiedName(app))
+			}
+			unhealthy = true
+		}
+		if op := app.Status.OperationState; op != nil && (op.Phase == synccommon.OperationFailed || op.Phase == synccommon.OperationError) {
+			unhealthyApps.OperationFailed = append(unhealthyApps.OperationFailed, qualifiedName(app))
+			unhealthy = true
+		}
+		if unhealthy && in.Verbose {
+			unhealthyApps.Applications = append(unhealthyApps.Applications, newApplicationSummary(app))
 		}
 	}
 
@@ -103,3 +244,158 @@ func listUnhealthyApplications(ctx context.Context, logger *slog.Logger, cl Clie
 	}
 	return unhealthyApps, nil
 }
+
+// maybeUnhealthy returns true if the given (projected) application is not healthy, not synced or if its last operation failed
+func maybeUnhealthy(app argocdv3.Application) bool {
+	switch app.Status.Health.Status {
+	case "", argocdhealth.HealthStatusHealthy:
+	default:
+		return true
+	}
+	switch app.Status.Sync.Status {
+	case argocdv3.SyncStatusCodeOutOfSync, argocdv3.SyncStatusCodeUnknown:
+		return true
+	}
+	op := app.Status.OperationState
+	return op != nil && (op.Phase == synccommon.OperationFailed || op.Phase == synccommon.OperationError)
+}
+
+// getApplications returns the full version of the given applications, fetched concurrently from Argo CD.
+// The applications which were deleted in the meantime are skipped.
+func getApplications(ctx context.Context, cl Client, apps []argocdv3.Application) ([]argocdv3.Application, error) {
+	full := make([]*argocdv3.Application, len(apps))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrentApplicationGets)
+	for i, app := range apps {
+		g.Go(func() error {
+			a, err := cl.GetApplication(gctx, ApplicationRef{Name: app.Name, Namespace: app.Namespace})
+			if IsNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get application '%s' from Argo CD: %w", qualifiedName(app), err)
+			}
+			full[i] = a
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	result := make([]argocdv3.Application, 0, len(apps))
+	for _, a := range full {
+		if a != nil {
+			result = append(result, *a)
+		}
+	}
+	return result, nil
+}
+
+// listUnhealthyApplicationsInAllInstances lists the unhealthy applications of all the instances concurrently,
+// and merges the results. The instances which could not be queried are reported in the `Errors` of the result,
+// and an error is returned only if none of the instances could be queried.
+func listUnhealthyApplicationsInAllInstances(ctx context.Context, logger *slog.Logger, instances *Instances, in UnhealthyApplicationsInput) (UnhealthyApplications, error) {
+	if in.Instance != "" {
+		return UnhealthyApplications{}, fmt.Errorf("the 'instance' and 'allInstances' inputs cannot be combined: either query a single instance or all of them")
+	}
+	all := instances.All()
+	results := make([]UnhealthyApplications, len(all))
+	errs := make([]error, len(all))
+	wg := sync.WaitGroup{}
+	for i, instance := range all {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = listUnhealthyApplications(ctx, logger, instance.Client, in)
+		}()
+	}
+	wg.Wait()
+
+	unhealthyApps := UnhealthyApplications{}
+	for i, instance := range all {
+		if errs[i] != nil {
+			if unhealthyApps.Errors == nil {
+				unhealthyApps.Errors = map[string]string{}
+			}
+			unhealthyApps.Errors[instance.Name] = errs[i].Error()
+			continue
+		}
+		unhealthyApps.add(instance.Name, results[i])
+	}
+	if len(unhealthyApps.Errors) == len(all) {
+		return UnhealthyApplications{}, errors.Join(errs...)
+	}
+	return unhealthyApps, nil
+}
+
+// add adds the given unhealthy applications of the given instance, prefixing their names with the name of the instance
+func (a *UnhealthyApplications) add(instance string, other UnhealthyApplications) {
+	tag := func(names []string) []string {
+		tagged := make([]string, 0, len(names))
+		for _, n := range names {
+			tagged = append(tagged, instance+":"+n)
+		}
+		return tagged
+	}
+	a.Health.Degraded = append(a.Health.Degraded, tag(other.Health.Degraded)...)
+	a.Health.Progressing = append(a.Health.Progressing, tag(other.Health.Progressing)...)
+	a.Health.Missing = append(a.Health.Missing, tag(other.Health.Missing)...)
+	a.Health.Unknown = append(a.Health.Unknown, tag(other.Health.Unknown)...)
+	a.Health.Suspended = append(a.Health.Suspended, tag(other.Health.Suspended)...)
+	a.Sync.OutOfSync = append(a.Sync.OutOfSync, tag(other.Sync.OutOfSync)...)
+	a.Sync.Unknown = append(a.Sync.Unknown, tag(other.Sync.Unknown)...)
+	a.Conditions.ComparisonError = append(a.Conditions.ComparisonError, tag(other.Conditions.ComparisonError)...)
+	a.Conditions.SyncError = append(a.Conditions.SyncError, tag(other.Conditions.SyncError)...)
+	a.Conditions.InvalidSpecError = append(a.Conditions.InvalidSpecError, tag(other.Conditions.InvalidSpecError)...)
+	a.Conditions.OtherError = append(a.Conditions.OtherError, tag(other.Conditions.OtherError)...)
+	a.OperationFailed = append(a.OperationFailed, tag(other.OperationFailed)...)
+	for _, s := range other.Applications {
+		s.Instance = instance
+		a.Applications = append(a.Applications, s)
+	}
+}
+
+// errorConditionTypes returns the distinct types of the error conditions of the given application
+func errorConditionTypes(app argocdv3.Application) []string {
+	types := []string{}
+	for _, c := range app.Status.Conditions {
+		if c.IsError() && !slices.Contains(types, c.Type) {
+			types = append(types, c.Type)
+		}
+	}
+	return types
+}
+
+func newApplicationSummary(app argocdv3.Application) ApplicationSummary {
+	s := ApplicationSummary{
+		Name:      app.Name,
+		Namespace: app.Namespace,
+		Project:   app.Spec.Project,
+		Destination: DestinationSummary{
+			Server:    app.Spec.Destination.Server,
+			Name:      app.Spec.Destination.Name,
+			Namespace: app.Spec.Destination.Namespace,
+		},
+		Health: HealthSummary{
+			Status:  string(app.Status.Health.Status),
+			Message: app.Status.Health.Message,
+		},
+		Sync: SyncSummary{
+			Status:   string(app.Status.Sync.Status),
+			Revision: app.Status.Sync.Revision,
+		},
+	}
+	if op := app.Status.OperationState; op != nil {
+		s.LastOperation = &OperationSummary{
+			Phase:      string(op.Phase),
+			Message:    op.Message,
+			FinishedAt: formatTime(op.FinishedAt),
+		}
+	}
+	for _, c := range app.Status.Conditions {
+		if c.IsError() {
+			s.Conditions = append(s.Conditions, fmt.Sprintf("%s: %s", c.Type, c.Message))
+		}
+	}
+	return s
+}
@@ -2,32 +2,626 @@ package argocd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestListUnhealthyApplications(t *testing.T) {
-	// given
-	cl := &FakeArgoCDClient{}
+
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
 	}))
 
+	t.Run("compact", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		unhealthyApps, err := listUnhealthyApplications(context.Background(), logger, cl, UnhealthyApplicationsInput{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, UnhealthyApplications{
+			Health: HealthBuckets{
+				Degraded:    []string{"argocd/a-degraded-application", "argocd/another-degraded-application"},
+				Progressing: []string{"argocd/a-progressing-application", "argocd/another-progressing-application"},
+				Missing:     nil, // TODO: add missing applications
+				Unknown:     nil, // TODO: add unknown applications
+				Suspended:   nil, // TODO: add suspended applications
+			},
+			Sync: SyncBuckets{
+				// health and sync status are independent
+				OutOfSync: []string{"argocd/an-out-of-sync-application", "argocd/another-out-of-sync-application", "argocd/another-degraded-application"},
+				Unknown:   []string{"argocd/another-progressing-application"},
+			},
+			Conditions: ConditionBuckets{
+				ComparisonError: []string{"argocd/another-degraded-application"},
+			},
+			OperationFailed: []string{"argocd/a-degraded-application"},
+		}, unhealthyApps)
+	})
+
+	t.Run("verbose", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		unhealthyApps, err := listUnhealthyApplications(context.Background(), logger, cl, UnhealthyApplicationsInput{
+			Verbose: true,
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"argocd/a-degraded-application", "argocd/another-degraded-application"}, unhealthyApps.Health.Degraded)
+		require.Len(t, unhealthyApps.Applications, 6)
+		assert.Equal(t, ApplicationSummary{
+			Name:      "an-out-of-sync-application",
+			Namespace: "argocd",
+			Health: HealthSummary{
+				Status: "Healthy",
+			},
+			Sync: SyncSummary{
+				Status: "OutOfSync",
+			},
+		}, unhealthyApps.Applications[0])
+		assert.Equal(t, ApplicationSummary{
+			Name:      "a-degraded-application",
+			Namespace: "argocd",
+			Project:   "team-a",
+			Destination: DestinationSummary{
+				Server:    "https://kubernetes.default.svc",
+				Namespace: "team-a-dev",
+			},
+			Health: HealthSummary{
+				Status:  "Degraded",
+				Message: `Deployment "a-degraded-application" exceeded its progress deadline`,
+			},
+			Sync: SyncSummary{
+				Status:   "Synced",
+				Revision: "8f3c2a1d5e6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d",
+			},
+			LastOperation: &OperationSummary{
+				Phase:      "Failed",
+				Message:    `one or more objects failed to apply, reason: Deployment.apps "a-degraded-application" is invalid`,
+				FinishedAt: "2025-08-07T13:40:15Z",
+			},
+		}, unhealthyApps.Applications[2])
+		// warning conditions are not reported
+		assert.Equal(t, []string{
+			"ComparisonError: Failed to load target state: failed to generate manifest for source 1 of 1: rpc error: code = Unknown desc = Manifest generation error (cached): kustomize build failed",
+		}, unhealthyApps.Applications[3].Conditions)
+	})
+	t.Run("filtered", func(t *testing.T) {
+
+		testdata := []struct {
+			name     string
+			input    UnhealthyApplicationsInput
+			expected UnhealthyApplications
+		}{
+			{
+				name: "by project",
+				input: UnhealthyApplicationsInput{
+					Projects: []string{"team-a", "team-c"},
+				},
+				expected: UnhealthyApplications{
+					Health: HealthBuckets{
+						Degraded: []string{"argocd/a-degraded-application"},
+					},
+					OperationFailed: []string{"argocd/a-degraded-application"},
+				},
+			},
+			{
+				name: "by label selector",
+				input: UnhealthyApplicationsInput{
+					Selector: "team in (team-a,team-b)",
+				},
+				expected: UnhealthyApplications{
+					Health: HealthBuckets{
+						Degraded:    []string{"argocd/a-degraded-application"},
+						Progressing: []string{"argocd/a-progressing-application"},
+					},
+					OperationFailed: []string{"argocd/a-degraded-application"},
+				},
+			},
+			{
+				name: "by repository",
+				input: UnhealthyApplicationsInput{
+					RepoURL: "https://github.com/example/team-b-apps.git",
+				},
+				expected: UnhealthyApplications{
+					Health: HealthBuckets{
+						Progressing: []string{"argocd/a-progressing-application"},
+					},
+				},
+			},
+			{
+				name: "by destination server and namespace",
+				input: UnhealthyApplicationsInput{
+					DestinationServer:    "https://kubernetes.default.svc",
+					DestinationNamespace: "team-a-dev",
+				},
+				expected: UnhealthyApplications{
+					Health: HealthBuckets{
+						Degraded: []string{"argocd/a-degraded-application"},
+					},
+					OperationFailed: []string{"argocd/a-degraded-application"},
+				},
+			},
+			{
+				name: "by destination name",
+				input: UnhealthyApplicationsInput{
+					DestinationName: "production",
+				},
+				expected: UnhealthyApplications{
+					Health: HealthBuckets{
+						Progressing: []string{"argocd/a-progressing-application"},
+					},
+				},
+			},
+			{
+				name: "by app namespace",
+				input: UnhealthyApplicationsInput{
+					AppNamespace: "team-c",
+				},
+				expected: UnhealthyApplications{},
+			},
+		}
+
+		for _, td := range testdata {
+			t.Run(td.name, func(t *testing.T) {
+				// given
+				cl := &FakeArgoCDClient{}
+
+				// when
+				unhealthyApps, err := listUnhealthyApplications(context.Background(), logger, cl, td.input)
+
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, td.expected, unhealthyApps)
+			})
+		}
+	})
+
+	t.Run("invalid label selector", func(t *testing.T) {
+		// given
+		cl := &FakeArgoCDClient{}
+
+		// when
+		_, err := listUnhealthyApplications(context.Background(), logger, cl, UnhealthyApplicationsInput{
+			Selector: "team in (",
+		})
+
+		// then
+		require.ErrorContains(t, err, "failed to list applications from Argo CD")
+	})
+
+	t.Run("all instances", func(t *testing.T) {
+
+		t.Run("ok", func(t *testing.T) {
+			// given
+			instances, err := NewInstances(
+				Instance{Name: "dev", Client: &FakeArgoCDClient{}},
+				Instance{Name: "prod", Client: &FakeArgoCDClient{}},
+			)
+			require.NoError(t, err)
+
+			// when
+			unhealthyApps, err := listUnhealthyApplicationsInAllInstances(context.Background(), logger, instances, UnhealthyApplicationsInput{
+				Projects: []string{"team-a"},
+				Verbose:  true,
+			})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, []string{"dev:argocd/a-degraded-application", "prod:argocd/a-degraded-application"}, unhealthyApps.Health.Degraded)
+			assert.Equal(t, []string{"dev:argocd/a-degraded-application", "prod:argocd/a-degraded-application"}, unhealthyApps.OperationFailed)
+			assert.Nil(t, unhealthyApps.Sync.OutOfSync)
+			require.Len(t, unhealthyApps.Applications, 2)
+			assert.Equal(t, "dev", unhealthyApps.Applications[0].Instance)
+			assert.Equal(t, "prod", unhealthyApps.Applications[1].Instance)
+			assert.Empty(t, unhealthyApps.Errors)
+		})
+
+		t.Run("unreachable instance", func(t *testing.T) {
+			// given
+			instances, err := NewInstances(
+				Instance{Name: "dev", Client: &FakeArgoCDClient{}},
+				Instance{Name: "prod", Client: &unreachableArgoCDClient{}},
+			)
+			require.NoError(t, err)
+
+			// when
+			unhealthyApps, err := listUnhealthyApplicationsInAllInstances(context.Background(), logger, instances, UnhealthyApplicationsInput{
+				Projects: []string{"team-a"},
+			})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, []string{"dev:argocd/a-degraded-application"}, unhealthyApps.Health.Degraded)
+			assert.Equal(t, map[string]string{
+				"prod": "failed to list applications from Argo CD: connection refused",
+			}, unhealthyApps.Errors)
+		})
+
+		t.Run("all instances unreachable", func(t *testing.T) {
+			// given
+			instances, err := NewInstances(
+				Instance{Name: "dev", Client: &unreachableArgoCDClient{}},
+				Instance{Name: "prod", Client: &unreachableArgoCDClient{}},
+			)
+			require.NoError(t, err)
+
+			// when
+			_, err = listUnhealthyApplicationsInAllInstances(context.Background(), logger, instances, UnhealthyApplicationsInput{})
+
+			// then
+			require.EqualError(t, err, "failed to list applications from Argo CD: connection refused\nfailed to list applications from Argo CD: connection refused")
+		})
+
+		t.Run("instance and all instances", func(t *testing.T) {
+			// given
+			instances, err := NewInstances(
+				Instance{Name: "dev", Client: &FakeArgoCDClient{}},
+				Instance{Name: "prod", Client: &FakeArgoCDClient{}},
+			)
+			require.NoError(t, err)
+
+			// when
+			_, err = listUnhealthyApplicationsInAllInstances(context.Background(), logger, instances, UnhealthyApplicationsInput{
+				Instance:     "prod",
+				AllInstances: true,
+			})
+
+			// then
+			require.EqualError(t, err, "the 'instance' and 'allInstances' inputs cannot be combined: either query a single instance or all of them")
+		})
+	})
+}
+
+// unreachableArgoCDClient a fake client which fails to list the applications
+type unreachableArgoCDClient struct {
+	FakeArgoCDClient
+}
+
+func (c *unreachableArgoCDClient) ListApplications(_ context.Context, _ ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestListUnhealthyApplicationsFields(t *testing.T) {
+
+	// given
+	srv, requestedFields := newApplicationListServer(t, 100)
+	cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+	full, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+	require.NoError(t, err)
+	expected, err := listUnhealthyApplications(context.Background(), slog.New(slog.DiscardHandler), &listArgoCDClient{apps: full}, UnhealthyApplicationsInput{
+		Verbose: true,
+	})
+	require.NoError(t, err)
+
 	// when
-	unhealthyApps, err := listUnhealthyApplications(context.Background(), logger, cl)
+	actual, err := listUnhealthyApplications(context.Background(), slog.New(slog.DiscardHandler), cl, UnhealthyApplicationsInput{
+		Verbose: true,
+	})
 
-	// then
+	// then the projected applications contain all the fields needed to list the unhealthy applications
 	require.NoError(t, err)
-	assert.Equal(t, UnhealthyApplications{
-		Degraded:    []string{"a-degraded-application", "another-degraded-application"},
-		Progressing: []string{"a-progressing-application", "another-progressing-application"},
-		OutOfSync:   []string{"an-out-of-sync-application", "another-out-of-sync-application"},
-		Missing:     nil, // TODO: add missing applications
-		Unknown:     nil, // TODO: add unknown applications
-		Suspended:   nil, // TODO: add suspended applications
-	}, unhealthyApps)
+	assert.Equal(t, unhealthyApplicationsFields, *requestedFields)
+	assert.NotEmpty(t, actual.Applications)
+	assert.NotEmpty(t, actual.Conditions.ComparisonError)
+	assert.Equal(t, expected, actual)
+}
+
+func TestProjectedApplicationFields(t *testing.T) {
+
+	logger := slog.New(slog.DiscardHandler)
+	srv, _ := newApplicationListServer(t, 100)
+	cl := newTestClient(t, srv.URL, StaticToken("secure-token"))
+
+	t.Run("unhealthy application resources", func(t *testing.T) {
+		// when
+		resources, err := listUnhealthyApplicationResources(context.Background(), logger, cl, ApplicationRef{Name: "app-0002"})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, resources.Resources, 1)
+		assert.Equal(t, health.HealthStatusDegraded, resources.Resources[0].Health.Status)
+	})
+
+	t.Run("application history", func(t *testing.T) {
+		// when
+		history, err := listApplicationHistory(context.Background(), logger, cl, ApplicationRef{Name: "app-0002"})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, history.History, 10)
+		assert.Equal(t, "https://github.com/example/apps.git", history.History[0].Source.RepoURL)
+	})
+}
+
+// BenchmarkListUnhealthyApplications measures the memory used to list the unhealthy applications among 5,000 applications,
+// with all the fields of the applications (as before the projection), and with the projected fields only
+func BenchmarkListUnhealthyApplications(b *testing.B) {
+	srv, _ := newApplicationListServer(b, 5000)
+	cl := newTestClient(b, srv.URL, StaticToken("secure-token"))
+	logger := slog.New(slog.DiscardHandler)
+
+	b.Run("all fields", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			apps, err := cl.ListApplications(context.Background(), ListApplicationsOptions{})
+			require.NoError(b, err)
+			_, err = listUnhealthyApplications(context.Background(), logger, &listArgoCDClient{apps: apps}, UnhealthyApplicationsInput{})
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("projected fields", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_, err := listUnhealthyApplications(context.Background(), logger, cl, UnhealthyApplicationsInput{})
+			require.NoError(b, err)
+		}
+	})
+}
+
+// listArgoCDClient returns the given applications, regardless of the options
+type listArgoCDClient struct {
+	Client
+	apps *argocdv3.ApplicationList
+}
+
+func (c *listArgoCDClient) ListApplications(_ context.Context, _ ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	return c.apps, nil
+}
+
+func (c *listArgoCDClient) GetApplication(_ context.Context, ref ApplicationRef) (*argocdv3.Application, error) {
+	for _, app := range c.apps.Items {
+		if app.Name == ref.Name && app.Namespace == ref.Namespace {
+			return &app, nil
+		}
+	}
+	return nil, &APIError{
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+// argoCDApplicationFields the fields of the applications which Argo CD projects when the `fields` query param is set,
+// copied from `appFields` in Argo CD's `server/application/forwarder_overwrite.go`
+var argoCDApplicationFields = []string{
+	"metadata.name",
+	"metadata.namespace",
+	"metadata.annotations",
+	"metadata.labels",
+	"metadata.creationTimestamp",
+	"metadata.deletionTimestamp",
+	"spec",
+	"status.sync.status",
+	"status.health",
+	"status.summary",
+	"status.operationState.startedAt",
+	"status.operationState.finishedAt",
+	"status.resources",
+	"operation.sync",
+	"status.operationState.phase",
+	"status.operationState.operation.sync",
+}
+
+// newApplicationListServer returns an Argo CD server which lists the given number of generated applications, with their
+// fields projected as Argo CD does when the `fields` query param is set (ie: the metadata of the list and the allowed
+// fields of the applications only). The requested fields are recorded. The server also returns each (full) application.
+func newApplicationListServer(t testing.TB, count int) (*httptest.Server, *[]string) {
+	apps := newApplicationListFixture(count)
+	full, err := json.Marshal(apps)
+	require.NoError(t, err)
+	var doc any
+	require.NoError(t, json.Unmarshal(full, &doc))
+	projected := map[string][]byte{}
+	requestedFields := &[]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/applications", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		fields := query.Get("fields")
+		if fields == "" && query.Get("name") == "" {
+			_, _ = w.Write(full)
+			return
+		}
+		*requestedFields = strings.Split(fields, ",")
+		if _, found := projected[r.URL.RawQuery]; !found {
+			list := doc
+			if name := query.Get("name"); name != "" {
+				list = filterItems(doc, name)
+			}
+			if fields != "" {
+				paths := [][]string{{"metadata"}}
+				for _, f := range *requestedFields {
+					if f, found := strings.CutPrefix(f, "items."); found && slices.Contains(argoCDApplicationFields, f) {
+						paths = append(paths, strings.Split("items."+f, "."))
+					}
+				}
+				list = projectFields(list, paths)
+			}
+			data, err := json.Marshal(list)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			projected[r.URL.RawQuery] = data
+		}
+		_, _ = w.Write(projected[r.URL.RawQuery])
+	})
+	mux.HandleFunc("GET /api/v1/applications/{name}", func(w http.ResponseWriter, r *http.Request) {
+		for _, app := range apps.Items {
+			if app.Name == r.PathValue("name") {
+				_ = json.NewEncoder(w).Encode(app)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, requestedFields
+}
+
+// filterItems returns a copy of the given JSON list with the items with the given name only
+func filterItems(doc any, name string) any {
+	list := maps.Clone(doc.(map[string]any))
+	items := []any{}
+	for _, i := range list["items"].([]any) {
+		if i.(map[string]any)["metadata"].(map[string]any)["name"] == name {
+			items = append(items, i)
+		}
+	}
+	list["items"] = items
+	return list
+}
+
+// projectFields returns a copy of the given JSON document with the given paths only. The elements of the arrays
+// are projected with the same paths (eg: `items.metadata.name` retains the name of all the items).
+func projectFields(doc any, paths [][]string) any {
+	switch v := doc.(type) {
+	case []any:
+		result := make([]any, len(v))
+		for i, e := range v {
+			result[i] = projectFields(e, paths)
+		}
+		return result
+	case map[string]any:
+		result := map[string]any{}
+		children := map[string][][]string{}
+		for _, p := range paths {
+			if _, found := v[p[0]]; !found {
+				continue
+			}
+			if len(p) == 1 {
+				result[p[0]] = v[p[0]]
+				continue
+			}
+			children[p[0]] = append(children[p[0]], p[1:])
+		}
+		for k, p := range children {
+			if _, found := result[k]; !found {
+				result[k] = projectFields(v[k], p)
+			}
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// newApplicationListFixture returns a list of applications with various health and sync statuses, conditions
+// and operation states, each one with a realistic number of managed resources and deployments in its history
+func newApplicationListFixture(count int) *argocdv3.ApplicationList {
+	healthStatuses := []health.HealthStatusCode{health.HealthStatusHealthy, health.HealthStatusHealthy, health.HealthStatusDegraded, health.HealthStatusProgressing, health.HealthStatusMissing}
+	syncStatuses := []argocdv3.SyncStatusCode{argocdv3.SyncStatusCodeSynced, argocdv3.SyncStatusCodeSynced, argocdv3.SyncStatusCodeOutOfSync, argocdv3.SyncStatusCodeUnknown}
+	finishedAt := metav1.Now()
+	apps := &argocdv3.ApplicationList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "159091",
+		},
+	}
+	for i := range count {
+		app := argocdv3.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("app-%04d", i),
+				Namespace: "argocd",
+				Labels: map[string]string{
+					"team": fmt.Sprintf("team-%d", i%10),
+				},
+			},
+			Spec: argocdv3.ApplicationSpec{
+				Project: fmt.Sprintf("team-%d", i%10),
+				Source: &argocdv3.ApplicationSource{
+					RepoURL:        "https://github.com/example/apps.git",
+					Path:           fmt.Sprintf("apps/app-%04d", i),
+					TargetRevision: "main",
+				},
+				Destination: argocdv3.ApplicationDestination{
+					Server:    "https://kubernetes.default.svc",
+					Namespace: fmt.Sprintf("app-%04d", i),
+				},
+			},
+			Status: argocdv3.ApplicationStatus{
+				Health: argocdv3.HealthStatus{
+					Status: healthStatuses[i%len(healthStatuses)],
+				},
+				Sync: argocdv3.SyncStatus{
+					Status:   syncStatuses[i%len(syncStatuses)],
+					Revision: fmt.Sprintf("%040d", i),
+				},
+				OperationState: &argocdv3.OperationState{
+					Phase:      synccommon.OperationSucceeded,
+					Message:    "successfully synced (all tasks run)",
+					FinishedAt: &finishedAt,
+					SyncResult: &argocdv3.SyncOperationResult{
+						Revision: fmt.Sprintf("%040d", i),
+					},
+				},
+			},
+		}
+		if i%7 == 0 {
+			app.Status.Conditions = append(app.Status.Conditions, argocdv3.ApplicationCondition{
+				Type:    argocdv3.ApplicationConditionComparisonError,
+				Message: "Failed to load target state: failed to generate manifest",
+			})
+		}
+		if i%11 == 0 {
+			app.Status.OperationState.Phase = synccommon.OperationFailed
+			app.Status.OperationState.Message = "one or more objects failed to apply"
+		}
+		for j := range 30 {
+			resource := argocdv3.ResourceStatus{
+				Version:   "v1",
+				Kind:      "ConfigMap",
+				Namespace: app.Spec.Destination.Namespace,
+				Name:      fmt.Sprintf("config-%02d", j),
+				Status:    argocdv3.SyncStatusCodeSynced,
+				Health: &argocdv3.HealthStatus{
+					Status: health.HealthStatusHealthy,
+				},
+			}
+			if j == 0 {
+				// the first resource has the health status of the application
+				resource.Health.Status = app.Status.Health.Status
+			}
+			app.Status.Resources = append(app.Status.Resources, resource)
+			app.Status.OperationState.SyncResult.Resources = append(app.Status.OperationState.SyncResult.Resources, &argocdv3.ResourceResult{
+				Version:   resource.Version,
+				Kind:      resource.Kind,
+				Namespace: resource.Namespace,
+				Name:      resource.Name,
+				Status:    synccommon.ResultCodeSynced,
+				Message:   fmt.Sprintf("configmap/%s configured", resource.Name),
+				SyncPhase: synccommon.SyncPhaseSync,
+			})
+		}
+		for j := range 10 {
+			app.Status.History = append(app.Status.History, argocdv3.RevisionHistory{
+				ID:         int64(j),
+				Revision:   fmt.Sprintf("%040d", j),
+				DeployedAt: finishedAt,
+				Source:     *app.Spec.Source,
+			})
+		}
+		apps.Items = append(apps.Items, app)
+	}
+	return apps
 }
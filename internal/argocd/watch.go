@@ -0,0 +1,230 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// watchMinBackoff and watchMaxBackoff the bounds of the delay before reconnecting to the stream of application events
+	watchMinBackoff = time.Second
+	watchMaxBackoff = time.Minute
+)
+
+// NewWatchedClient returns a client which answers the requests to list and get applications from an in-memory copy
+// of all the applications, which is kept up-to-date with the stream of application events of Argo CD (`api/v1/stream/applications`),
+// until the given context is done. The other requests, and the requests received before the copy is synced, are sent to the given client.
+//
+// When the stream is closed (eg: by a proxy with an idle timeout) or fails, the applications are listed again before watching them again,
+// since Argo CD does not replay the events which occurred while the stream was closed.
+func NewWatchedClient(ctx context.Context, cl Client, logger *slog.Logger) Client {
+	w := newWatchedClient(cl, logger)
+	go w.run(ctx)
+	return w
+}
+
+func newWatchedClient(cl Client, logger *slog.Logger) *watchedClient {
+	return &watchedClient{
+		Client:     cl,
+		logger:     logger,
+		minBackoff: watchMinBackoff,
+		maxBackoff: watchMaxBackoff,
+		apps:       map[string]map[string]*argocdv3.Application{},
+	}
+}
+
+type watchedClient struct {
+	Client
+	logger     *slog.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	mu         sync.RWMutex
+	synced     bool
+	// resourceVersion the resource version of the list or of the last application event
+	resourceVersion string
+	// apps the applications, indexed by name and namespace
+	apps map[string]map[string]*argocdv3.Application
+}
+
+var _ Client = &watchedClient{}
+
+// ListApplications returns the applications from the in-memory copy, unless the application with the given name (if any)
+// is not found (eg: it was just created and its event was not received yet), in which case the request is sent to Argo CD
+func (w *watchedClient) ListApplications(ctx context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	if cacheDisabled(ctx) || !w.isSynced() {
+		return w.Client.ListApplications(ctx, opts)
+	}
+	selector, err := labels.Parse(opts.Selector)
+	if err != nil {
+		return nil, &APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("error parsing the selector: %s", err.Error()),
+		}
+	}
+	result := w.listApplications(opts, selector)
+	if opts.Name != "" && len(result.Items) == 0 {
+		return w.Client.ListApplications(ctx, opts)
+	}
+	return result, nil
+}
+
+func (w *watchedClient) listApplications(opts ListApplicationsOptions, selector labels.Selector) *argocdv3.ApplicationList {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	result := &argocdv3.ApplicationList{
+		Items: []argocdv3.Application{},
+	}
+	result.ResourceVersion = w.resourceVersion
+	add := func(apps map[string]*argocdv3.Application) {
+		for _, app := range apps {
+			if opts.matches(app, selector) {
+				result.Items = append(result.Items, *app.DeepCopy())
+			}
+		}
+	}
+	if opts.Name != "" {
+		add(w.apps[opts.Name])
+	} else {
+		for _, apps := range w.apps {
+			add(apps)
+		}
+	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		if result.Items[i].Name != result.Items[j].Name {
+			return result.Items[i].Name < result.Items[j].Name
+		}
+		return result.Items[i].Namespace < result.Items[j].Namespace
+	})
+	return result
+}
+
+// GetApplication returns the application from the in-memory copy, unless it is not found (or not unique when the namespace is not specified),
+// in which case the request is sent to Argo CD, which knows its own namespace and returns the appropriate error.
+func (w *watchedClient) GetApplication(ctx context.Context, ref ApplicationRef) (*argocdv3.Application, error) {
+	if cacheDisabled(ctx) || !w.isSynced() {
+		return w.Client.GetApplication(ctx, ref)
+	}
+	w.mu.RLock()
+	apps := w.apps[ref.Name]
+	var app *argocdv3.Application
+	switch {
+	case ref.Namespace != "":
+		app = apps[ref.Namespace]
+	case len(apps) == 1:
+		for _, a := range apps {
+			app = a
+		}
+	}
+	if app != nil {
+		app = app.DeepCopy()
+	}
+	w.mu.RUnlock()
+	if app == nil {
+		return w.Client.GetApplication(ctx, ref)
+	}
+	return app, nil
+}
+
+func (w *watchedClient) isSynced() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.synced
+}
+
+// run lists and watches the applications until the context is done
+func (w *watchedClient) run(ctx context.Context) {
+	backoff := w.minBackoff
+	for ctx.Err() == nil {
+		if !w.isSynced() {
+			if err := w.list(ctx); err != nil {
+				w.logger.WarnContext(ctx, "failed to list the applications to watch", "error", err.Error(), "delay", backoff.String())
+				if sleep(ctx, backoff) != nil {
+					return
+				}
+				backoff = min(2*backoff, w.maxBackoff)
+				continue
+			}
+			w.logger.DebugContext(ctx, "synced the applications", "resourceVersion", w.resourceVersion)
+		}
+		received := false
+		err := w.Client.WatchApplications(ctx, w.resourceVersion, func(e argocdv3.ApplicationWatchEvent) bool {
+			received = true
+			w.apply(e)
+			return true
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			backoff = w.minBackoff
+		}
+		if err != nil {
+			w.logger.WarnContext(ctx, "failed to watch the applications, listing them again", "error", err.Error(), "delay", backoff.String())
+		} else {
+			w.logger.DebugContext(ctx, "application stream closed, listing the applications again", "delay", backoff.String())
+		}
+		// the events which occur until the stream is reopened are not replayed by Argo CD
+		w.mu.Lock()
+		w.synced = false
+		w.mu.Unlock()
+		if sleep(ctx, backoff) != nil {
+			return
+		}
+		backoff = min(2*backoff, w.maxBackoff)
+	}
+}
+
+// list replaces the in-memory copy with the applications listed from Argo CD
+func (w *watchedClient) list(ctx context.Context) error {
+	list, err := w.Client.ListApplications(WithoutCache(ctx), ListApplicationsOptions{})
+	if err != nil {
+		return err
+	}
+	apps := map[string]map[string]*argocdv3.Application{}
+	for i := range list.Items {
+		app := &list.Items[i]
+		if apps[app.Name] == nil {
+			apps[app.Name] = map[string]*argocdv3.Application{}
+		}
+		apps[app.Name][app.Namespace] = app
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.apps = apps
+	w.resourceVersion = list.ResourceVersion
+	w.synced = true
+	return nil
+}
+
+// apply applies the given event to the in-memory copy
+func (w *watchedClient) apply(e argocdv3.ApplicationWatchEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	app := e.Application
+	switch e.Type {
+	case watch.Added, watch.Modified:
+		if w.apps[app.Name] == nil {
+			w.apps[app.Name] = map[string]*argocdv3.Application{}
+		}
+		w.apps[app.Name][app.Namespace] = &app
+	case watch.Deleted:
+		delete(w.apps[app.Name], app.Namespace)
+		if len(w.apps[app.Name]) == 0 {
+			delete(w.apps, app.Name)
+		}
+	default:
+		return
+	}
+	if app.ResourceVersion != "" {
+		w.resourceVersion = app.ResourceVersion
+	}
+}
@@ -0,0 +1,361 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestWatchedClient(t *testing.T) {
+
+	newApplication := func(name, resourceVersion string, status health.HealthStatusCode) argocdv3.Application {
+		return argocdv3.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "argocd",
+				ResourceVersion: resourceVersion,
+			},
+			Status: argocdv3.ApplicationStatus{
+				Health: argocdv3.HealthStatus{
+					Status: status,
+				},
+			},
+		}
+	}
+
+	// start starts watching the applications with the given client, and waits until the applications are synced
+	start := func(t *testing.T, cl *watchingArgoCDClient) *watchedClient {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		w := newWatchedClient(cl, slog.New(slog.DiscardHandler))
+		w.minBackoff = time.Millisecond
+		w.maxBackoff = time.Millisecond
+		go w.run(ctx)
+		require.Eventually(t, w.isSynced, time.Second, time.Millisecond)
+		return w
+	}
+
+	t.Run("not synced", func(t *testing.T) {
+		// given
+		cl := &watchingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+		}
+		w := newWatchedClient(cl, slog.New(slog.DiscardHandler))
+
+		// when
+		apps, err := w.ListApplications(context.Background(), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, apps.Items, 8)
+		assert.Equal(t, int32(1), cl.lists.Load()) // sent to Argo CD
+	})
+
+	t.Run("synced", func(t *testing.T) {
+		// given
+		cl := &watchingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+		}
+		w := start(t, cl)
+
+		// when
+		apps, err := w.ListApplications(context.Background(), ListApplicationsOptions{
+			Projects: []string{"team-a", "team-b"},
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), cl.lists.Load()) // only to sync
+		names := []string{}
+		for _, app := range apps.Items {
+			names = append(names, app.Name)
+		}
+		assert.Equal(t, []string{"a-degraded-application", "a-progressing-application"}, names)
+		assert.Equal(t, []string{"159091"}, cl.watchedResourceVersions())
+	})
+
+	t.Run("events", func(t *testing.T) {
+		// given
+		cl := &watchingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+			streams: []watchStream{
+				{
+					events: []argocdv3.ApplicationWatchEvent{
+						{Type: watch.Added, Application: newApplication("a-new-application", "159092", health.HealthStatusMissing)},
+						{Type: watch.Modified, Application: newApplication("a-degraded-application", "159093", health.HealthStatusHealthy)},
+						{Type: watch.Deleted, Application: newApplication("an-healthy-application", "159094", health.HealthStatusHealthy)},
+					},
+					open: true,
+				},
+			},
+		}
+		w := start(t, cl)
+
+		// when
+		require.Eventually(t, func() bool {
+			w.mu.RLock()
+			defer w.mu.RUnlock()
+			return w.resourceVersion == "159094" // all the events were received
+		}, time.Second, time.Millisecond)
+
+		// then
+		apps, err := w.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+		assert.Len(t, apps.Items, 8)
+		app, err := w.GetApplication(context.Background(), ApplicationRef{Name: "a-new-application"})
+		require.NoError(t, err)
+		assert.Equal(t, health.HealthStatusMissing, app.Status.Health.Status)
+		app, err = w.GetApplication(context.Background(), ApplicationRef{Name: "a-degraded-application", Namespace: "argocd"})
+		require.NoError(t, err)
+		assert.Equal(t, health.HealthStatusHealthy, app.Status.Health.Status)
+		_, err = w.GetApplication(context.Background(), ApplicationRef{Name: "an-healthy-application"})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), cl.gets.Load()) // sent to Argo CD (which still has it, in this fake)
+		_, err = w.ListApplications(context.Background(), ListApplicationsOptions{Name: "an-healthy-application"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"159091"}, cl.watchedResourceVersions())
+		assert.Equal(t, int32(2), cl.lists.Load()) // to sync and to look up the deleted application
+	})
+
+	t.Run("closed stream", func(t *testing.T) {
+		// given
+		cl := &watchingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+			streams: []watchStream{
+				{}, // closed without any event
+			},
+			deleted: []string{"an-healthy-application"}, // while the stream was closed
+		}
+		w := start(t, cl)
+
+		// when
+		require.Eventually(t, func() bool {
+			return len(cl.watchedResourceVersions()) == 2
+		}, time.Second, time.Millisecond)
+
+		// then the applications were listed again, so the deleted application is not returned
+		assert.Equal(t, int32(2), cl.lists.Load())
+		assert.Equal(t, []string{"159091", "159091"}, cl.watchedResourceVersions())
+		apps, err := w.ListApplications(context.Background(), ListApplicationsOptions{})
+		require.NoError(t, err)
+		assert.Len(t, apps.Items, 7)
+		for _, app := range apps.Items {
+			assert.NotEqual(t, "an-healthy-application", app.Name)
+		}
+	})
+
+	t.Run("failed stream", func(t *testing.T) {
+		// given
+		cl := &watchingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+			streams: []watchStream{
+				{
+					events: []argocdv3.ApplicationWatchEvent{
+						{Type: watch.Added, Application: newApplication("a-new-application", "159092", health.HealthStatusMissing)},
+					},
+					err: fmt.Errorf("unexpected EOF"),
+				},
+			},
+		}
+		start(t, cl)
+
+		// when
+		require.Eventually(t, func() bool {
+			return len(cl.watchedResourceVersions()) == 2
+		}, time.Second, time.Millisecond)
+
+		// then the applications were listed again, and watched from the resource version of the list
+		assert.Equal(t, int32(2), cl.lists.Load())
+		assert.Equal(t, []string{"159091", "159091"}, cl.watchedResourceVersions())
+	})
+
+	t.Run("without cache", func(t *testing.T) {
+		// given
+		cl := &watchingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+		}
+		w := start(t, cl)
+
+		// when
+		_, err := w.ListApplications(WithoutCache(context.Background()), ListApplicationsOptions{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), cl.lists.Load())
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		// given
+		cl := &watchingArgoCDClient{
+			Client: &FakeArgoCDClient{},
+		}
+		w := start(t, cl)
+
+		// when
+		_, err := w.ListApplications(context.Background(), ListApplicationsOptions{
+			Selector: "team in (a",
+		})
+
+		// then
+		apiErr := &APIError{}
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	})
+}
+
+func TestClientWatchApplications(t *testing.T) {
+
+	newServer := func(t *testing.T, body string) (*httptest.Server, *[]string) {
+		requests := &[]string{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*requests = append(*requests, r.URL.String())
+			if r.Header.Get("Authorization") != "Bearer secure-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(srv.Close)
+		return srv, requests
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		// given
+		srv, requests := newServer(t, `{"result":{"type":"ADDED","application":{"metadata":{"name":"example","namespace":"argocd","resourceVersion":"1"}}}}
+{"result":{"type":"DELETED","application":{"metadata":{"name":"example","namespace":"argocd","resourceVersion":"2"}}}}
+`)
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{
+			Timeout: time.Second,
+		})
+		require.NoError(t, err)
+		events := []argocdv3.ApplicationWatchEvent{}
+
+		// when
+		err = cl.WatchApplications(context.Background(), "159091", func(e argocdv3.ApplicationWatchEvent) bool {
+			events = append(events, e)
+			return true
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/api/v1/stream/applications?resourceVersion=159091"}, *requests)
+		require.Len(t, events, 2)
+		assert.Equal(t, watch.Added, events[0].Type)
+		assert.Equal(t, "example", events[0].Application.Name)
+		assert.Equal(t, watch.Deleted, events[1].Type)
+		assert.Equal(t, "2", events[1].Application.ResourceVersion)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		// given
+		srv, _ := newServer(t, `{"error":{"grpc_code":7,"http_code":403,"message":"permission denied","http_status":"Forbidden"}}
+`)
+		cl, err := NewClient(srv.URL, StaticToken("secure-token"), ClientOptions{})
+		require.NoError(t, err)
+
+		// when
+		err = cl.WatchApplications(context.Background(), "", func(_ argocdv3.ApplicationWatchEvent) bool {
+			return true
+		})
+
+		// then
+		apiErr := &APIError{}
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+		assert.Equal(t, "permission denied", apiErr.Message)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		// given
+		srv, _ := newServer(t, "")
+		cl, err := NewClient(srv.URL, StaticToken("invalid-token"), ClientOptions{})
+		require.NoError(t, err)
+
+		// when
+		err = cl.WatchApplications(context.Background(), "", func(_ argocdv3.ApplicationWatchEvent) bool {
+			return true
+		})
+
+		// then
+		require.True(t, IsUnauthorized(err))
+	})
+}
+
+// watchingArgoCDClient counts the calls to `ListApplications` and `GetApplication`, and sends the events of the given streams
+// on the successive calls to `WatchApplications`. Once all the streams were sent, the last call blocks until the context is done.
+type watchingArgoCDClient struct {
+	Client
+	lists   atomic.Int32
+	gets    atomic.Int32
+	streams []watchStream
+	// deleted the names of the applications which are not returned after the first list
+	deleted          []string
+	mu               sync.Mutex
+	resourceVersions []string
+}
+
+type watchStream struct {
+	events []argocdv3.ApplicationWatchEvent
+	err    error
+	// open keeps the stream open after the events, until the context is done
+	open bool
+}
+
+func (c *watchingArgoCDClient) ListApplications(ctx context.Context, opts ListApplicationsOptions) (*argocdv3.ApplicationList, error) {
+	n := c.lists.Add(1)
+	apps, err := c.Client.ListApplications(ctx, opts)
+	if err != nil || n == 1 {
+		return apps, err
+	}
+	apps.Items = slices.DeleteFunc(apps.Items, func(app argocdv3.Application) bool {
+		return slices.Contains(c.deleted, app.Name)
+	})
+	return apps, nil
+}
+
+func (c *watchingArgoCDClient) GetApplication(ctx context.Context, ref ApplicationRef) (*argocdv3.Application, error) {
+	c.gets.Add(1)
+	return c.Client.GetApplication(ctx, ref)
+}
+
+func (c *watchingArgoCDClient) WatchApplications(ctx context.Context, resourceVersion string, handle func(argocdv3.ApplicationWatchEvent) bool) error {
+	c.mu.Lock()
+	c.resourceVersions = append(c.resourceVersions, resourceVersion)
+	n := len(c.resourceVersions)
+	c.mu.Unlock()
+	if n > len(c.streams) {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	s := c.streams[n-1]
+	for _, e := range s.events {
+		if !handle(e) {
+			return nil
+		}
+	}
+	if s.open {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.err
+}
+
+func (c *watchingArgoCDClient) watchedResourceVersions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.resourceVersions...)
+}
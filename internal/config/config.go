@@ -0,0 +1,366 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// Config the configuration of the Argo CD MCP server. Each setting has a corresponding flag,
+// except the instances, which can only be configured in the file.
+type Config struct {
+	Transport       string `json:"transport,omitempty"`
+	Listen          string `json:"listen,omitempty"`
+	Debug           *bool  `json:"debug,omitempty"`
+	ArgoCDURL       string `json:"argocdURL,omitempty"`
+	ArgoCDToken     string `json:"argocdToken,omitempty"`
+	ArgoCDTokenFile string `json:"argocdTokenFile,omitempty"`
+	ArgoCDUsername  string `json:"argocdUsername,omitempty"`
+	ArgoCDPassword  string `json:"argocdPassword,omitempty"`
+	Insecure        *bool  `json:"insecure,omitempty"`
+	// ArgoCDCAFile the path to the PEM-encoded CA bundle to verify the certificate of the Argo CD server
+	ArgoCDCAFile string `json:"argocdCAFile,omitempty"`
+	// ArgoCDClientCert and ArgoCDClientKey the paths to the PEM-encoded client certificate and key, for mutual TLS
+	ArgoCDClientCert string `json:"argocdClientCert,omitempty"`
+	ArgoCDClientKey  string `json:"argocdClientKey,omitempty"`
+	// ArgoCDTLSMinVersion the minimum TLS version (`1.2` by default)
+	ArgoCDTLSMinVersion string `json:"argocdTLSMinVersion,omitempty"`
+	// ArgoCDServerName the server name to use for SNI and to verify the certificate of the Argo CD server
+	ArgoCDServerName string `json:"argocdServerName,omitempty"`
+	// ArgoCDBasePath the path under which Argo CD is served (eg: `/argocd` when the API server runs with `--rootpath=/argocd`)
+	ArgoCDBasePath string `json:"argocdBasePath,omitempty"`
+	// ArgoCDProxy the URL of the HTTP proxy to connect to Argo CD (defaults to the `HTTPS_PROXY` and `HTTP_PROXY` env vars)
+	ArgoCDProxy string `json:"argocdProxy,omitempty"`
+	// ArgoCDRateLimit, ArgoCDRateLimitBurst and ArgoCDMaxInFlight the limits of the requests to Argo CD:
+	// the average number of requests per second, the max number of requests sent at once, and the max number of requests in flight
+	ArgoCDRateLimit      *float64 `json:"argocdRateLimit,omitempty"`
+	ArgoCDRateLimitBurst *int     `json:"argocdRateLimitBurst,omitempty"`
+	ArgoCDMaxInFlight    *int     `json:"argocdMaxInFlight,omitempty"`
+	// ArgoCDNamespace the namespace of the Argo CD control plane, to read the Applications through the Kubernetes API
+	// instead of the Argo CD API server (in which case the `argocdURL` and credentials must not be set)
+	ArgoCDNamespace string `json:"argocdNamespace,omitempty"`
+	// ArgoCDKubeconfig and ArgoCDKubeContext the kubeconfig file and context to connect to the Kubernetes API server
+	// (defaults to the `KUBECONFIG` env var, `~/.kube/config` and its current context, or the in-cluster configuration)
+	ArgoCDKubeconfig  string `json:"argocdKubeconfig,omitempty"`
+	ArgoCDKubeContext string `json:"argocdKubeContext,omitempty"`
+	// Timeout the timeout of each attempt of a request to Argo CD (eg: `30s`), which applies to all the instances
+	Timeout string `json:"timeout,omitempty"`
+	// MaxRetries, RetryInitialBackoff, RetryMaxBackoff and RetryMaxTime the settings of the retries of the idempotent requests
+	// which failed with a transient error, which apply to all the instances
+	MaxRetries          *int   `json:"maxRetries,omitempty"`
+	RetryInitialBackoff string `json:"retryInitialBackoff,omitempty"`
+	RetryMaxBackoff     string `json:"retryMaxBackoff,omitempty"`
+	RetryMaxTime        string `json:"retryMaxTime,omitempty"`
+	// CacheTTL how long the responses of Argo CD are cached (eg: `10s`, or `0s` to disable the cache)
+	CacheTTL string `json:"cacheTTL,omitempty"`
+	// UncachedTools the tools which never use the cache of the Argo CD responses
+	UncachedTools []string `json:"uncachedTools,omitempty"`
+	// WatchApplications keeps an in-memory copy of all the Applications, updated with the stream of Application events of Argo CD
+	WatchApplications *bool `json:"watchApplications,omitempty"`
+	// Instances the Argo CD instances to query, instead of the single `argocdURL` instance. The first one is the default instance.
+	Instances []Instance `json:"instances,omitempty"`
+}
+
+// Instance the connection settings of an Argo CD instance
+type Instance struct {
+	Name      string `json:"name"`
+	URL       string `json:"url,omitempty"`
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+	// Username and Password the credentials of the Argo CD local user to log in as, instead of using a token
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+	// CAFile, ClientCert, ClientKey, TLSMinVersion and ServerName: same as the `argocd*` settings of the single instance
+	CAFile        string `json:"caFile,omitempty"`
+	ClientCert    string `json:"clientCert,omitempty"`
+	ClientKey     string `json:"clientKey,omitempty"`
+	TLSMinVersion string `json:"tlsMinVersion,omitempty"`
+	ServerName    string `json:"serverName,omitempty"`
+	// BasePath and Proxy: same as the `argocdBasePath` and `argocdProxy` settings of the single instance
+	BasePath string `json:"basePath,omitempty"`
+	Proxy    string `json:"proxy,omitempty"`
+	// RateLimit, RateLimitBurst and MaxInFlight: same as the `argocdRateLimit`, `argocdRateLimitBurst` and `argocdMaxInFlight` settings of the single instance
+	RateLimit      float64 `json:"rateLimit,omitempty"`
+	RateLimitBurst int     `json:"rateLimitBurst,omitempty"`
+	MaxInFlight    int     `json:"maxInFlight,omitempty"`
+	// Namespace, Kubeconfig and KubeContext: same as the `argocdNamespace`, `argocdKubeconfig` and `argocdKubeContext` settings of the single instance
+	Namespace   string `json:"namespace,omitempty"`
+	Kubeconfig  string `json:"kubeconfig,omitempty"`
+	KubeContext string `json:"kubeContext,omitempty"`
+}
+
+// Load loads the configuration from the YAML (or JSON) file at the given path
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read the configuration file: %w", err)
+	}
+	cfg := Config{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse the configuration file '%s': %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration file '%s': %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that the instances (if any) have a unique name, and either a URL and a token, a token file or a username and password,
+// or a namespace to read the Applications through the Kubernetes API, and that they are not combined with the settings of the single `argocdURL` instance
+func (c Config) Validate() error {
+	if len(c.Instances) > 0 {
+		if settings := c.singleInstanceSettings(); len(settings) > 0 {
+			return fmt.Errorf("'instances' cannot be combined with '%s'", strings.Join(settings, "', '"))
+		}
+	}
+	names := map[string]bool{}
+	for i, instance := range c.Instances {
+		switch {
+		case instance.Name == "":
+			return fmt.Errorf("missing name of instance #%d", i)
+		case names[instance.Name]:
+			return fmt.Errorf("duplicate instance name '%s'", instance.Name)
+		case instance.Namespace != "":
+			if settings := instance.apiServerSettings(); len(settings) > 0 {
+				return fmt.Errorf("'%s' of instance '%s' cannot be combined with its namespace", strings.Join(settings, "', '"), instance.Name)
+			}
+		case instance.URL == "":
+			return fmt.Errorf("missing URL or namespace of instance '%s'", instance.Name)
+		default:
+			if err := ValidateCredentials(instance.Token, instance.TokenFile, instance.Username, instance.Password); err != nil {
+				return fmt.Errorf("invalid credentials of instance '%s': %w", instance.Name, err)
+			}
+		}
+		names[instance.Name] = true
+	}
+	return nil
+}
+
+// apiServerSettings returns the names of the settings of the instance which only apply to the Argo CD API server
+// (URL, credentials, TLS, proxy and rate limits) and which are set
+func (i Instance) apiServerSettings() []string {
+	settings := []string{}
+	for _, s := range []struct {
+		name string
+		set  bool
+	}{
+		{"url", i.URL != ""},
+		{"token", i.Token != ""},
+		{"tokenFile", i.TokenFile != ""},
+		{"username", i.Username != ""},
+		{"password", i.Password != ""},
+		{"insecure", i.Insecure},
+		{"caFile", i.CAFile != ""},
+		{"clientCert", i.ClientCert != ""},
+		{"clientKey", i.ClientKey != ""},
+		{"tlsMinVersion", i.TLSMinVersion != ""},
+		{"serverName", i.ServerName != ""},
+		{"basePath", i.BasePath != ""},
+		{"proxy", i.Proxy != ""},
+		{"rateLimit", i.RateLimit != 0},
+		{"rateLimitBurst", i.RateLimitBurst != 0},
+		{"maxInFlight", i.MaxInFlight != 0},
+	} {
+		if s.set {
+			settings = append(settings, s.name)
+		}
+	}
+	return settings
+}
+
+// singleInstanceSettings returns the names of the settings of the single `argocdURL` instance which are set
+func (c Config) singleInstanceSettings() []string {
+	settings := []string{}
+	for _, s := range []struct {
+		name string
+		set  bool
+	}{
+		{"argocdURL", c.ArgoCDURL != ""},
+		{"argocdToken", c.ArgoCDToken != ""},
+		{"argocdTokenFile", c.ArgoCDTokenFile != ""},
+		{"argocdUsername", c.ArgoCDUsername != ""},
+		{"argocdPassword", c.ArgoCDPassword != ""},
+		{"insecure", c.Insecure != nil},
+		{"argocdCAFile", c.ArgoCDCAFile != ""},
+		{"argocdClientCert", c.ArgoCDClientCert != ""},
+		{"argocdClientKey", c.ArgoCDClientKey != ""},
+		{"argocdTLSMinVersion", c.ArgoCDTLSMinVersion != ""},
+		{"argocdServerName", c.ArgoCDServerName != ""},
+		{"argocdBasePath", c.ArgoCDBasePath != ""},
+		{"argocdProxy", c.ArgoCDProxy != ""},
+		{"argocdRateLimit", c.ArgoCDRateLimit != nil},
+		{"argocdRateLimitBurst", c.ArgoCDRateLimitBurst != nil},
+		{"argocdMaxInFlight", c.ArgoCDMaxInFlight != nil},
+		{"argocdNamespace", c.ArgoCDNamespace != ""},
+		{"argocdKubeconfig", c.ArgoCDKubeconfig != ""},
+		{"argocdKubeContext", c.ArgoCDKubeContext != ""},
+	} {
+		if s.set {
+			settings = append(settings, s.name)
+		}
+	}
+	return settings
+}
+
+// ValidateCredentials checks that exactly one kind of credentials is set: a token, a token file, or a username and password
+func ValidateCredentials(token, tokenFile, username, password string) error {
+	set := 0
+	for _, s := range []string{token, tokenFile, username} {
+		if s != "" {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return fmt.Errorf("either a token, a token file or a username and password must be set")
+	case set > 1:
+		return fmt.Errorf("only one of the token, the token file or the username and password can be set")
+	case username != "" && password == "":
+		return fmt.Errorf("the password must be set along with the username")
+	case username == "" && password != "":
+		return fmt.Errorf("the username must be set along with the password")
+	}
+	return nil
+}
+
+// flagValues returns the values of the settings which are set in the file, indexed by the name of their flag
+func (c Config) flagValues() map[string]string {
+	values := map[string]string{}
+	for name, value := range map[string]string{
+		"transport":              c.Transport,
+		"listen":                 c.Listen,
+		"argocd-url":             c.ArgoCDURL,
+		"argocd-token":           c.ArgoCDToken,
+		"argocd-token-file":      c.ArgoCDTokenFile,
+		"argocd-username":        c.ArgoCDUsername,
+		"argocd-password":        c.ArgoCDPassword,
+		"argocd-ca-file":         c.ArgoCDCAFile,
+		"argocd-client-cert":     c.ArgoCDClientCert,
+		"argocd-client-key":      c.ArgoCDClientKey,
+		"argocd-tls-min-version": c.ArgoCDTLSMinVersion,
+		"argocd-server-name":     c.ArgoCDServerName,
+		"argocd-base-path":       c.ArgoCDBasePath,
+		"argocd-proxy":           c.ArgoCDProxy,
+		"argocd-namespace":       c.ArgoCDNamespace,
+		"argocd-kubeconfig":      c.ArgoCDKubeconfig,
+		"argocd-kube-context":    c.ArgoCDKubeContext,
+		"timeout":                c.Timeout,
+		"retry-initial-backoff":  c.RetryInitialBackoff,
+		"retry-max-backoff":      c.RetryMaxBackoff,
+		"retry-max-time":         c.RetryMaxTime,
+		"cache-ttl":              c.CacheTTL,
+		"uncached-tools":         strings.Join(c.UncachedTools, ","),
+	} {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	for name, value := range map[string]*bool{
+		"debug":              c.Debug,
+		"insecure":           c.Insecure,
+		"watch-applications": c.WatchApplications,
+	} {
+		if value != nil {
+			values[name] = strconv.FormatBool(*value)
+		}
+	}
+	for name, value := range map[string]*int{
+		"max-retries":             c.MaxRetries,
+		"argocd-rate-limit-burst": c.ArgoCDRateLimitBurst,
+		"argocd-max-in-flight":    c.ArgoCDMaxInFlight,
+	} {
+		if value != nil {
+			values[name] = strconv.Itoa(*value)
+		}
+	}
+	if c.ArgoCDRateLimit != nil {
+		values["argocd-rate-limit"] = strconv.FormatFloat(*c.ArgoCDRateLimit, 'f', -1, 64)
+	}
+	return values
+}
+
+// EnvPrefix the prefix of the environment variables bound to the flags
+const EnvPrefix = "ARGOCD_MCP_"
+
+// EnvVar returns the name of the environment variable bound to the flag with the given name,
+// without the redundant `argocd-` prefix (eg: `ARGOCD_MCP_URL` for `--argocd-url` and `ARGOCD_MCP_DEBUG` for `--debug`)
+func EnvVar(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(strings.TrimPrefix(flag, "argocd-"), "-", "_"))
+}
+
+// nonConfigFlags the flags which are not settings, and thus are not bound to an environment variable nor to the file
+var nonConfigFlags = []string{"help"}
+
+// legacyEnvVars the environment variables of the previous versions of the container image, which are still supported
+// for backward compatibility, indexed by the name of their flag, along with the func to convert their value.
+// They have a lower precedence than the environment variable bound to the flag.
+var legacyEnvVars = map[string]struct {
+	name    string
+	convert func(string) string
+}{
+	"listen": {
+		name: EnvPrefix + "LISTEN_PORT",
+		convert: func(port string) string {
+			return ":" + port
+		},
+	},
+}
+
+// Bind sets the flags which were not set on the command line from their environment variable, or else from
+// the configuration file whose path is set with the `config` flag (or its environment variable).
+// That is, the precedence order is: flag > env > file.
+// Returns the configuration loaded from the file, or an empty configuration if there is no file.
+func Bind(flags *pflag.FlagSet, lookupEnv func(string) (string, bool)) (Config, error) {
+	// the path to the configuration file can be set by the environment variable, but obviously not by the file itself
+	configFlag := flags.Lookup("config")
+	if err := bindFlag(flags, configFlag, lookupEnv, nil); err != nil {
+		return Config{}, err
+	}
+	cfg := Config{}
+	if path := configFlag.Value.String(); path != "" {
+		var err error
+		if cfg, err = Load(path); err != nil {
+			return Config{}, err
+		}
+	}
+	fileValues := cfg.flagValues()
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err == nil && !slices.Contains(nonConfigFlags, f.Name) {
+			err = bindFlag(flags, f, lookupEnv, fileValues)
+		}
+	})
+	return cfg, err
+}
+
+func bindFlag(flags *pflag.FlagSet, f *pflag.Flag, lookupEnv func(string) (string, bool), fileValues map[string]string) error {
+	if f.Changed {
+		return nil
+	}
+	if value, found := lookupEnv(EnvVar(f.Name)); found {
+		if err := flags.Set(f.Name, value); err != nil {
+			return fmt.Errorf("invalid value of the '%s' environment variable: %w", EnvVar(f.Name), err)
+		}
+		return nil
+	}
+	if legacy, found := legacyEnvVars[f.Name]; found {
+		if value, found := lookupEnv(legacy.name); found {
+			if err := flags.Set(f.Name, legacy.convert(value)); err != nil {
+				return fmt.Errorf("invalid value of the '%s' environment variable: %w", legacy.name, err)
+			}
+			return nil
+		}
+	}
+	if value, found := fileValues[f.Name]; found {
+		if err := flags.Set(f.Name, value); err != nil {
+			return fmt.Errorf("invalid value in the configuration file for the '--%s' flag: %w", f.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,424 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+
+	t.Run("ok", func(t *testing.T) {
+		// given
+		path := writeFile(t, `instances:
+- name: dev
+  url: https://argocd.dev
+  token: dev-token
+  insecure: true
+- name: prod
+  url: https://argocd.prod
+  token: prod-token
+  basePath: /argocd
+  proxy: http://proxy.example.com:3128
+  rateLimit: 2.5
+  maxInFlight: 10
+- name: staging
+  url: https://argocd.staging
+  username: admin
+  password: secret
+- name: qa
+  namespace: argocd
+  kubeContext: qa
+`)
+
+		// when
+		cfg, err := Load(path)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Config{
+			Instances: []Instance{
+				{
+					Name:     "dev",
+					URL:      "https://argocd.dev",
+					Token:    "dev-token",
+					Insecure: true,
+				},
+				{
+					Name:        "prod",
+					URL:         "https://argocd.prod",
+					Token:       "prod-token",
+					BasePath:    "/argocd",
+					Proxy:       "http://proxy.example.com:3128",
+					RateLimit:   2.5,
+					MaxInFlight: 10,
+				},
+				{
+					Name:     "staging",
+					URL:      "https://argocd.staging",
+					Username: "admin",
+					Password: "secret",
+				},
+				{
+					Name:        "qa",
+					Namespace:   "argocd",
+					KubeContext: "qa",
+				},
+			},
+		}, cfg)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		// given
+		path := writeFile(t, `{"instances":[{"name":"dev","url":"https://argocd.dev","token":"dev-token"}]}`)
+
+		// when
+		cfg, err := Load(path)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, cfg.Instances, 1)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		// when
+		_, err := Load(filepath.Join(t.TempDir(), "config.yaml"))
+
+		// then
+		require.ErrorContains(t, err, "failed to read the configuration file")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		// given
+		path := writeFile(t, `instances:
+- name: dev
+  url: https://argocd.dev
+  tokn: dev-token
+`)
+
+		// when
+		_, err := Load(path)
+
+		// then
+		require.ErrorContains(t, err, `unknown field "tokn"`)
+	})
+
+	testdata := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{
+			name: "missing name",
+			content: `instances:
+- url: https://argocd.dev
+  token: dev-token`,
+			expectedError: "missing name of instance #0",
+		},
+		{
+			name: "duplicate name",
+			content: `instances:
+- name: dev
+  url: https://argocd.dev
+  token: dev-token
+- name: dev
+  url: https://argocd.prod
+  token: prod-token`,
+			expectedError: "duplicate instance name 'dev'",
+		},
+		{
+			name: "missing url",
+			content: `instances:
+- name: dev
+  token: dev-token`,
+			expectedError: "missing URL or namespace of instance 'dev'",
+		},
+		{
+			name: "missing token",
+			content: `instances:
+- name: dev
+  url: https://argocd.dev`,
+			expectedError: "invalid credentials of instance 'dev': either a token, a token file or a username and password must be set",
+		},
+		{
+			name: "token and token file",
+			content: `instances:
+- name: dev
+  url: https://argocd.dev
+  token: dev-token
+  tokenFile: /var/run/secrets/argocd/token`,
+			expectedError: "invalid credentials of instance 'dev': only one of the token, the token file or the username and password can be set",
+		},
+		{
+			name: "instances and single instance settings",
+			content: `argocdURL: https://argocd.dev
+argocdCAFile: /etc/argocd/ca.crt
+instances:
+- name: dev
+  url: https://argocd.dev
+  token: dev-token`,
+			expectedError: "'instances' cannot be combined with 'argocdURL', 'argocdCAFile'",
+		},
+		{
+			name: "namespace and URL",
+			content: `instances:
+- name: dev
+  url: https://argocd.dev
+  token: dev-token
+  namespace: argocd`,
+			expectedError: "'url', 'token' of instance 'dev' cannot be combined with its namespace",
+		},
+		{
+			name: "namespace and proxy",
+			content: `instances:
+- name: dev
+  namespace: argocd
+  insecure: true
+  proxy: http://proxy.example.com:3128
+  rateLimit: 10`,
+			expectedError: "'insecure', 'proxy', 'rateLimit' of instance 'dev' cannot be combined with its namespace",
+		},
+		{
+			name: "username without password",
+			content: `instances:
+- name: dev
+  url: https://argocd.dev
+  username: admin`,
+			expectedError: "invalid credentials of instance 'dev': the password must be set along with the username",
+		},
+	}
+	for _, td := range testdata {
+		t.Run(td.name, func(t *testing.T) {
+			// given
+			path := writeFile(t, td.content)
+
+			// when
+			_, err := Load(path)
+
+			// then
+			require.ErrorContains(t, err, td.expectedError)
+		})
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	assert.Equal(t, "ARGOCD_MCP_URL", EnvVar("argocd-url"))
+	assert.Equal(t, "ARGOCD_MCP_TOKEN_FILE", EnvVar("argocd-token-file"))
+	assert.Equal(t, "ARGOCD_MCP_DEBUG", EnvVar("debug"))
+	assert.Equal(t, "ARGOCD_MCP_CONFIG", EnvVar("config"))
+}
+
+func TestBind(t *testing.T) {
+
+	path := writeFile(t, `transport: stdio
+listen: :9090
+debug: true
+argocdURL: https://argocd.file
+argocdToken: file-token
+argocdProxy: http://proxy.example.com:3128
+argocdRateLimit: 0.5
+timeout: 1m
+maxRetries: 5
+retryMaxTime: 2m
+watchApplications: true
+uncachedTools:
+- applicationEvents
+- resourceDiff
+`)
+
+	t.Run("flag > env > file", func(t *testing.T) {
+		// given
+		flags, settings := newFlagSet()
+		env := map[string]string{
+			"ARGOCD_MCP_CONFIG": path,
+			"ARGOCD_MCP_URL":    "https://argocd.env",
+			"ARGOCD_MCP_TOKEN":  "env-token",
+		}
+		err := flags.Parse([]string{"--argocd-token", "flag-token"})
+		require.NoError(t, err)
+
+		// when
+		cfg, err := Bind(flags, lookupEnv(env))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "https://argocd.file", cfg.ArgoCDURL)
+		assert.Equal(t, map[string]string{
+			"config":                path,
+			"transport":             "stdio",                            // from the file
+			"listen":                ":9090",                            // from the file
+			"debug":                 "true",                             // from the file
+			"insecure":              "false",                            // default value
+			"argocd-url":            "https://argocd.env",               // from the env
+			"argocd-token":          "flag-token",                       // from the flag
+			"argocd-token-file":     "",                                 // default value
+			"argocd-username":       "",                                 // default value
+			"argocd-password":       "",                                 // default value
+			"argocd-namespace":      "",                                 // default value
+			"argocd-proxy":          "http://proxy.example.com:3128",    // from the file
+			"argocd-rate-limit":     "0.5",                              // from the file
+			"argocd-max-in-flight":  "0",                                // default value
+			"timeout":               "1m0s",                             // from the file
+			"max-retries":           "5",                                // from the file
+			"retry-initial-backoff": "500ms",                            // default value
+			"retry-max-backoff":     "10s",                              // default value
+			"retry-max-time":        "2m0s",                             // from the file
+			"watch-applications":    "true",                             // from the file
+			"cache-ttl":             "10s",                              // default value
+			"uncached-tools":        "[applicationEvents,resourceDiff]", // from the file
+		}, settings())
+	})
+
+	t.Run("no file", func(t *testing.T) {
+		// given
+		flags, settings := newFlagSet()
+		env := map[string]string{
+			"ARGOCD_MCP_URL":           "https://argocd.env",
+			"ARGOCD_MCP_INSECURE":      "true",
+			"ARGOCD_MCP_MAX_RETRIES":   "0",
+			"ARGOCD_MCP_NAMESPACE":     "argocd",
+			"ARGOCD_MCP_MAX_IN_FLIGHT": "4",
+		}
+
+		// when
+		cfg, err := Bind(flags, lookupEnv(env))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Config{}, cfg)
+		assert.Equal(t, "https://argocd.env", settings()["argocd-url"])
+		assert.Equal(t, "true", settings()["insecure"])
+		assert.Equal(t, "0", settings()["max-retries"])
+		assert.Equal(t, "argocd", settings()["argocd-namespace"])
+		assert.Equal(t, "4", settings()["argocd-max-in-flight"])
+		assert.Equal(t, "http", settings()["transport"])
+	})
+
+	t.Run("legacy env var", func(t *testing.T) {
+		// given
+		flags, settings := newFlagSet()
+		env := map[string]string{
+			"ARGOCD_MCP_LISTEN_PORT": "9090",
+		}
+
+		// when
+		_, err := Bind(flags, lookupEnv(env))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, ":9090", settings()["listen"])
+	})
+
+	t.Run("env var over legacy env var", func(t *testing.T) {
+		// given
+		flags, settings := newFlagSet()
+		env := map[string]string{
+			"ARGOCD_MCP_LISTEN":      "localhost:8081",
+			"ARGOCD_MCP_LISTEN_PORT": "9090",
+		}
+
+		// when
+		_, err := Bind(flags, lookupEnv(env))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "localhost:8081", settings()["listen"])
+	})
+
+	t.Run("help is not bound", func(t *testing.T) {
+		// given
+		flags, settings := newFlagSet()
+		flags.Bool("help", false, "")
+		env := map[string]string{
+			"ARGOCD_MCP_HELP": "true",
+		}
+
+		// when
+		_, err := Bind(flags, lookupEnv(env))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "false", settings()["help"])
+	})
+
+	t.Run("invalid env var", func(t *testing.T) {
+		// given
+		flags, _ := newFlagSet()
+		env := map[string]string{
+			"ARGOCD_MCP_DEBUG": "maybe",
+		}
+
+		// when
+		_, err := Bind(flags, lookupEnv(env))
+
+		// then
+		require.ErrorContains(t, err, "invalid value of the 'ARGOCD_MCP_DEBUG' environment variable")
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		// given
+		flags, _ := newFlagSet()
+		err := flags.Parse([]string{"--config", writeFile(t, `argocd-url: https://argocd.file`)})
+		require.NoError(t, err)
+
+		// when
+		_, err = Bind(flags, lookupEnv(nil))
+
+		// then
+		require.ErrorContains(t, err, `unknown field "argocd-url"`)
+	})
+}
+
+// newFlagSet returns a flag set with the same flags as the `argocd-mcp` command,
+// and a func to get the values of all the flags
+func newFlagSet() (*pflag.FlagSet, func() map[string]string) {
+	flags := pflag.NewFlagSet("argocd-mcp", pflag.ContinueOnError)
+	flags.String("config", "", "")
+	flags.String("argocd-url", "", "")
+	flags.String("argocd-token", "", "")
+	flags.String("argocd-token-file", "", "")
+	flags.String("argocd-username", "", "")
+	flags.String("argocd-password", "", "")
+	flags.Bool("insecure", false, "")
+	flags.String("argocd-proxy", "", "")
+	flags.Float64("argocd-rate-limit", 0, "")
+	flags.Int("argocd-max-in-flight", 0, "")
+	flags.String("argocd-namespace", "", "")
+	flags.Duration("timeout", 30*time.Second, "")
+	flags.Int("max-retries", 3, "")
+	flags.Duration("retry-initial-backoff", 500*time.Millisecond, "")
+	flags.Duration("retry-max-backoff", 10*time.Second, "")
+	flags.Duration("retry-max-time", time.Minute, "")
+	flags.Duration("cache-ttl", 10*time.Second, "")
+	flags.StringSlice("uncached-tools", nil, "")
+	flags.Bool("watch-applications", false, "")
+	flags.Bool("debug", false, "")
+	flags.String("transport", "http", "")
+	flags.String("listen", ":8080", "")
+	return flags, func() map[string]string {
+		values := map[string]string{}
+		flags.VisitAll(func(f *pflag.Flag) {
+			values[f.Name] = f.Value.String()
+		})
+		return values
+	}
+}
+
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, found := env[key]
+		return value, found
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	require.NoError(t, err)
+	return path
+}
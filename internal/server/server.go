@@ -2,14 +2,25 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/codeready-toolchain/argocd-mcp/internal/argocd"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-func New(logger *slog.Logger, cl argocd.Client) *mcp.Server {
+// New returns a new MCP server with the Argo CD tools. The tools which need the Argo CD API server are not registered
+// if all the instances are read through the Kubernetes API. The given tools never use the cache of the Argo CD responses
+// (as if they were always called with `fresh: true`). When a tool call includes a progress token, the MCP client is notified
+// when the requests to Argo CD are delayed by the rate limit of the instance. The failed requests to Argo CD are not retried
+// once the given max retry time has elapsed since the start of the tool call (0 for no limit).
+// Returns an error if one of the uncached tools is not registered.
+func New(logger *slog.Logger, instances *argocd.Instances, uncachedTools []string, retryMaxTime time.Duration) (*mcp.Server, error) {
 	s := mcp.NewServer(
 		&mcp.Implementation{
 			Name:    "argocd-mcp",
@@ -22,8 +33,95 @@ func New(logger *slog.Logger, cl argocd.Client) *mcp.Server {
 		},
 	)
 
-	s.AddPrompt(argocd.UnhealthyResourcesPrompt, argocd.UnhealthyApplicationResourcesPromptHandle(logger, cl))
-	mcp.AddTool(s, argocd.UnhealthyApplicationsTool, argocd.UnhealthyApplicationsToolHandle(logger, cl))
-	mcp.AddTool(s, argocd.UnhealthyApplicationResourcesTool, argocd.UnhealthyApplicationResourcesToolHandle(logger, cl))
-	return s
+	mcp.AddTool(s, argocd.ListInstancesTool, argocd.ListInstancesToolHandle(logger, instances))
+	s.AddPrompt(argocd.UnhealthyResourcesPrompt, argocd.UnhealthyApplicationResourcesPromptHandle(logger, instances))
+	mcp.AddTool(s, argocd.UnhealthyApplicationsTool, argocd.UnhealthyApplicationsToolHandle(logger, instances))
+	mcp.AddTool(s, argocd.UnhealthyApplicationResourcesTool, argocd.UnhealthyApplicationResourcesToolHandle(logger, instances))
+	mcp.AddTool(s, argocd.ApplicationResourceTreeTool, argocd.ApplicationResourceTreeToolHandle(logger, instances))
+	mcp.AddTool(s, argocd.ApplicationEventsTool, argocd.ApplicationEventsToolHandle(logger, instances))
+	mcp.AddTool(s, argocd.ApplicationHistoryTool, argocd.ApplicationHistoryToolHandle(logger, instances))
+	tools := []string{
+		argocd.ListInstancesTool.Name,
+		argocd.UnhealthyApplicationsTool.Name,
+		argocd.UnhealthyApplicationResourcesTool.Name,
+		argocd.ApplicationResourceTreeTool.Name,
+		argocd.ApplicationEventsTool.Name,
+		argocd.ApplicationHistoryTool.Name,
+	}
+	if instances.HasAPIServer() {
+		mcp.AddTool(s, argocd.PodLogsTool, argocd.PodLogsToolHandle(logger, instances))
+		mcp.AddTool(s, argocd.ResourceDiffTool, argocd.ResourceDiffToolHandle(logger, instances))
+		mcp.AddTool(s, argocd.RefreshApplicationTool, argocd.RefreshApplicationToolHandle(logger, instances))
+		mcp.AddTool(s, argocd.SyncApplicationTool, argocd.SyncApplicationToolHandle(logger, instances))
+		mcp.AddTool(s, argocd.RollbackApplicationTool, argocd.RollbackApplicationToolHandle(logger, instances))
+		tools = append(tools,
+			argocd.PodLogsTool.Name,
+			argocd.ResourceDiffTool.Name,
+			argocd.RefreshApplicationTool.Name,
+			argocd.SyncApplicationTool.Name,
+			argocd.RollbackApplicationTool.Name,
+		)
+	}
+	for _, t := range uncachedTools {
+		if !slices.Contains(tools, t) {
+			return nil, fmt.Errorf("unknown uncached tool '%s' (the tools are: '%s')", t, strings.Join(tools, "', '"))
+		}
+	}
+	s.AddReceivingMiddleware(withProgress(logger))
+	if len(uncachedTools) > 0 {
+		s.AddReceivingMiddleware(withoutCache(uncachedTools))
+	}
+	if retryMaxTime > 0 {
+		s.AddReceivingMiddleware(withRetryDeadline(retryMaxTime))
+	}
+	return s, nil
+}
+
+// withProgress returns a middleware which sends a progress notification to the MCP client when a request to Argo CD
+// is delayed during a tool call, if the client asked for the progress of the call (ie: if the call has a progress token)
+func withProgress(logger *slog.Logger) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if r, ok := req.(*mcp.CallToolRequest); ok {
+				if token := r.Params.GetProgressToken(); token != nil {
+					progress := atomic.Int64{}
+					ctx = argocd.WithWaitNotifier(ctx, func(ctx context.Context, message string) {
+						if err := r.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+							ProgressToken: token,
+							Message:       message,
+							Progress:      float64(progress.Add(1)),
+						}); err != nil {
+							logger.DebugContext(ctx, "failed to send progress notification", "tool", r.Params.Name, "error", err.Error())
+						}
+					})
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// withoutCache returns a middleware which disables the cache of the Argo CD responses during the calls to the given tools
+func withoutCache(tools []string) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if r, ok := req.(*mcp.CallToolRequest); ok && slices.Contains(tools, r.Params.Name) {
+				ctx = argocd.WithoutCache(ctx)
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// withRetryDeadline returns a middleware which stops the retries of the failed requests to Argo CD
+// once the given max retry time has elapsed since the start of a tool call
+func withRetryDeadline(retryMaxTime time.Duration) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if _, ok := req.(*mcp.CallToolRequest); ok {
+				ctx = argocd.WithRetryDeadline(ctx, time.Now().Add(retryMaxTime))
+			}
+			return next(ctx, method, req)
+		}
+	}
 }
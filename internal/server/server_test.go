@@ -0,0 +1,51 @@
+package server
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/codeready-toolchain/argocd-mcp/internal/argocd"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+
+	logger := slog.New(slog.DiscardHandler)
+
+	t.Run("uncached tools", func(t *testing.T) {
+		// given
+		instances, err := argocd.NewInstances(argocd.Instance{Name: "default", Client: &argocd.FakeArgoCDClient{}})
+		require.NoError(t, err)
+
+		// when
+		_, err = New(logger, instances, []string{"applicationEvents", "resourceDiff"}, 0)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown uncached tool", func(t *testing.T) {
+		// given
+		instances, err := argocd.NewInstances(argocd.Instance{Name: "default", Client: &argocd.FakeArgoCDClient{}})
+		require.NoError(t, err)
+
+		// when
+		_, err = New(logger, instances, []string{"applicationEvents", "resourceDif"}, 0)
+
+		// then
+		require.ErrorContains(t, err, "unknown uncached tool 'resourceDif'")
+	})
+
+	t.Run("uncached tool not registered", func(t *testing.T) {
+		// given
+		instances, err := argocd.NewInstances(argocd.Instance{Name: "default", Client: &argocd.FakeArgoCDClient{}, KubernetesAPI: true})
+		require.NoError(t, err)
+
+		// when
+		_, err = New(logger, instances, []string{"resourceDiff"}, 0)
+
+		// then the tool is not registered when the Argo CD API server is not used
+		require.ErrorContains(t, err, "unknown uncached tool 'resourceDiff'")
+	})
+}
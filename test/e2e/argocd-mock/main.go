@@ -1,13 +1,18 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
+	argocdv3 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
 	"github.com/codeready-toolchain/argocd-mcp/test/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func main() {
@@ -24,6 +29,8 @@ func main() {
 		Level: lvl,
 	}))
 
+	// ops the state of the operations started on the applications, indexed by name
+	ops := sync.Map{}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/v1/applications", func(w http.ResponseWriter, r *http.Request) {
 		switch {
@@ -48,6 +55,83 @@ func main() {
 		_, _ = w.Write([]byte(resources.ApplicationsStr))
 	})
 
+	mux.HandleFunc("GET /api/v1/applications/{name}", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != fmt.Sprintf("Bearer %s", token):
+			logger.Debug("unauthorized request")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		app, err := getApplication(r.PathValue("name"))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if app == nil {
+			logger.Debug("application not found", "name", r.PathValue("name"))
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"application not found","code":5,"message":"application not found"}`))
+			return
+		}
+		if op, found := ops.Load(app.Name); found {
+			app.Status.OperationState = op.(*argocdv3.OperationState)
+		}
+		logger.Debug("serving application", "name", app.Name, "refresh", r.URL.Query().Get("refresh"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(app)
+	})
+
+	mux.HandleFunc("POST /api/v1/applications/{name}/sync", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != fmt.Sprintf("Bearer %s", token):
+			logger.Debug("unauthorized request")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case r.PathValue("name") != "example":
+			logger.Debug("application not found", "name", r.PathValue("name"))
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"application not found","code":5,"message":"application not found"}`))
+			return
+		}
+		req := map[string]any{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		app, err := exampleApplication()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// the sync operation is requested: the operation state is still the one of the previous operation,
+		// and the new operation is started (and completed) when the application is fetched again
+		app.Operation = &argocdv3.Operation{
+			Sync: &argocdv3.SyncOperation{},
+		}
+		if revision, ok := req["revision"].(string); ok {
+			app.Operation.Sync.Revision = revision
+		}
+		if dryRun, ok := req["dryRun"].(bool); ok {
+			app.Operation.Sync.DryRun = dryRun
+		}
+		now := metav1.Now()
+		ops.Store(app.Name, &argocdv3.OperationState{
+			Operation:  *app.Operation,
+			Phase:      synccommon.OperationSucceeded,
+			Message:    "successfully synced (all tasks run)",
+			StartedAt:  now,
+			FinishedAt: &now,
+			SyncResult: &argocdv3.SyncOperationResult{
+				Revision: app.Operation.Sync.Revision,
+			},
+		})
+		logger.Debug("syncing example application", "request", req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(app)
+	})
+
 	srv := &http.Server{
 		Addr:         listen,
 		Handler:      mux,
@@ -60,3 +144,28 @@ func main() {
 		panic(err)
 	}
 }
+
+func exampleApplication() (*argocdv3.Application, error) {
+	apps := &argocdv3.ApplicationList{}
+	if err := json.Unmarshal([]byte(resources.ExampleApplicationStr), apps); err != nil {
+		return nil, err
+	}
+	return &apps.Items[0], nil
+}
+
+// getApplication returns the example application or one of the mock applications with the given name, or nil if none matches
+func getApplication(name string) (*argocdv3.Application, error) {
+	if name == "example" {
+		return exampleApplication()
+	}
+	apps := &argocdv3.ApplicationList{}
+	if err := json.Unmarshal([]byte(resources.ApplicationsStr), apps); err != nil {
+		return nil, err
+	}
+	for _, app := range apps.Items {
+		if app.Name == name {
+			return &app, nil
+		}
+	}
+	return nil, nil
+}
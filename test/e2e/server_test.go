@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"testing"
 	"time"
@@ -60,6 +62,10 @@ func TestServer(t *testing.T) {
 			name: "http",
 			init: newHTTPSession("localhost:50081", true, "http://localhost:50084", "secure-token"),
 		},
+		{
+			name: "stdio-env",
+			init: newStdioSessionFromEnv(true, "http://localhost:50084", "secure-token"),
+		},
 	}
 
 	// test stdio and http transports with a valid Argo CD client
@@ -81,9 +87,18 @@ func TestServer(t *testing.T) {
 				require.False(t, result.IsError)
 				// expected content
 				expectedContent := map[string]any{
-					"degraded":    []any{"a-degraded-application", "another-degraded-application"},
-					"progressing": []any{"a-progressing-application", "another-progressing-application"},
-					"outOfSync":   []any{"an-out-of-sync-application", "another-out-of-sync-application"},
+					"health": map[string]any{
+						"degraded":    []any{"argocd/a-degraded-application", "argocd/another-degraded-application"},
+						"progressing": []any{"argocd/a-progressing-application", "argocd/another-progressing-application"},
+					},
+					"sync": map[string]any{
+						"outOfSync": []any{"argocd/an-out-of-sync-application", "argocd/another-out-of-sync-application", "argocd/another-degraded-application"},
+						"unknown":   []any{"argocd/another-progressing-application"},
+					},
+					"conditions": map[string]any{
+						"comparisonError": []any{"argocd/another-degraded-application"},
+					},
+					"operationFailed": []any{"argocd/a-degraded-application"},
 				}
 				expectedContentText, err := json.Marshal(expectedContent)
 				require.NoError(t, err)
@@ -99,6 +114,30 @@ func TestServer(t *testing.T) {
 				assert.Equal(t, expectedContent, actualStructuredContent)
 			})
 
+			t.Run("call/unhealthyApplications/verbose", func(t *testing.T) {
+				// when
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name: "unhealthyApplications",
+					Arguments: map[string]any{
+						"verbose": true,
+					},
+				})
+
+				// then
+				require.NoError(t, err)
+				require.False(t, result.IsError)
+				actualStructuredContent := argocd.UnhealthyApplications{}
+				err = runtime.DefaultUnstructuredConverter.FromUnstructured(result.StructuredContent.(map[string]any), &actualStructuredContent)
+				require.NoError(t, err)
+				require.Len(t, actualStructuredContent.Applications, 6)
+				i := slices.IndexFunc(actualStructuredContent.Applications, func(a argocd.ApplicationSummary) bool {
+					return a.Name == "a-degraded-application"
+				})
+				require.GreaterOrEqual(t, i, 0)
+				require.NotNil(t, actualStructuredContent.Applications[i].LastOperation)
+				assert.Equal(t, "Failed", actualStructuredContent.Applications[i].LastOperation.Phase)
+			})
+
 			t.Run("call/unhealthyApplicationResources/ok", func(t *testing.T) {
 				// when
 				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
@@ -173,6 +212,149 @@ func TestServer(t *testing.T) {
 				require.NoError(t, err)
 				assert.True(t, result.IsError)
 			})
+
+			t.Run("call/listInstances/ok", func(t *testing.T) {
+				// when
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name: "listInstances",
+				})
+
+				// then
+				require.NoError(t, err)
+				require.False(t, result.IsError)
+				expectedContent := argocd.InstanceList{
+					Instances: []argocd.InstanceSummary{
+						{
+							Name:    "default",
+							URL:     "http://localhost:50084",
+							Default: true,
+						},
+					},
+				}
+				expectedContentText, err := json.Marshal(expectedContent)
+				require.NoError(t, err)
+				resultContent, ok := result.Content[0].(*mcp.TextContent)
+				require.True(t, ok)
+				assert.JSONEq(t, string(expectedContentText), resultContent.Text)
+			})
+
+			t.Run("call/unhealthyApplications/unknown-instance", func(t *testing.T) {
+				// when
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name: "unhealthyApplications",
+					Arguments: map[string]any{
+						"instance": "unknown",
+					},
+				})
+
+				// then
+				require.NoError(t, err)
+				assert.True(t, result.IsError)
+			})
+
+			t.Run("call/refreshApplication/ok", func(t *testing.T) {
+				// when
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name: "refreshApplication",
+					Arguments: map[string]any{
+						"name": "example",
+						"hard": true,
+					},
+				})
+
+				// then
+				require.NoError(t, err)
+				require.False(t, result.IsError)
+				expectedContent := argocd.RefreshedApplication{
+					Name:      "example",
+					Namespace: "argocd",
+					Health: argocd.HealthSummary{
+						Status: "Progressing",
+					},
+					Sync: argocd.SyncSummary{
+						Status:   "OutOfSync",
+						Revision: "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+					},
+					ReconciledAt: "2025-08-07T13:48:00Z",
+				}
+				expectedContentText, err := json.Marshal(expectedContent)
+				require.NoError(t, err)
+				// verify the `text` result
+				resultContent, ok := result.Content[0].(*mcp.TextContent)
+				require.True(t, ok)
+				assert.JSONEq(t, string(expectedContentText), resultContent.Text)
+			})
+
+			t.Run("call/applicationHistory/ok", func(t *testing.T) {
+				// when
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name: "applicationHistory",
+					Arguments: map[string]any{
+						"name": "example",
+					},
+				})
+
+				// then
+				require.NoError(t, err)
+				require.False(t, result.IsError)
+				require.IsType(t, map[string]any{}, result.StructuredContent)
+				actualStructuredContent := argocd.ApplicationHistory{}
+				err = runtime.DefaultUnstructuredConverter.FromUnstructured(result.StructuredContent.(map[string]any), &actualStructuredContent)
+				require.NoError(t, err)
+				require.Len(t, actualStructuredContent.History, 2)
+				assert.Equal(t, int64(1), actualStructuredContent.History[0].ID)
+				assert.Equal(t, "3c1e9a4b5f6d7e8a9b0c1d2e3f4a5b6c7d8e9f01", actualStructuredContent.History[0].Revision)
+			})
+
+			t.Run("call/syncApplication/ok", func(t *testing.T) {
+				// when
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name: "syncApplication",
+					Arguments: map[string]any{
+						"name":     "example",
+						"revision": "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04",
+						"dryRun":   true,
+						"resources": []any{
+							map[string]any{
+								"group":     "external-secrets.io",
+								"kind":      "ExternalSecret",
+								"namespace": "example-ns",
+								"name":      "example-secret",
+							},
+						},
+					},
+				})
+
+				// then
+				require.NoError(t, err)
+				require.False(t, result.IsError)
+				// verify the `text` result
+				resultContent, ok := result.Content[0].(*mcp.TextContent)
+				require.True(t, ok)
+				actualContent := argocd.SyncApplicationOutput{}
+				err = json.Unmarshal([]byte(resultContent.Text), &actualContent)
+				require.NoError(t, err)
+				// the state of the operation started by Argo CD is returned
+				assert.False(t, actualContent.OperationState.Requested)
+				assert.Equal(t, "Succeeded", actualContent.OperationState.Phase)
+				assert.Equal(t, "f92d9eabe6aec90f46eec5d8eb79fcdcaef6aa04", actualContent.OperationState.Revision)
+				assert.True(t, actualContent.OperationState.DryRun)
+				assert.NotEmpty(t, actualContent.OperationState.FinishedAt)
+			})
+
+			t.Run("call/syncApplication/argocd-error", func(t *testing.T) {
+				// when
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name: "syncApplication",
+					Arguments: map[string]any{
+						"name": "unknown",
+					},
+				})
+
+				// then
+				require.NoError(t, err)
+				assert.True(t, result.IsError)
+			})
 		})
 	}
 
@@ -227,6 +409,30 @@ func newStdioSession(mcpServerListen string, mcpServerDebug bool, argocdURL stri
 	}
 }
 
+// newStdioSessionFromEnv starts the server with the `ARGOCD_MCP_*` environment variables instead of the flags,
+// and with the token in a file
+func newStdioSessionFromEnv(mcpServerDebug bool, argocdURL string, argocdToken string) func(*testing.T) (*mcp.ClientSession, KillMCPServerFunc) {
+	return func(t *testing.T) (*mcp.ClientSession, KillMCPServerFunc) {
+		ctx := context.Background()
+		tokenFile := filepath.Join(t.TempDir(), "token")
+		err := os.WriteFile(tokenFile, []byte(argocdToken+"\n"), 0o600)
+		require.NoError(t, err)
+		cmd := exec.CommandContext(ctx, "argocd-mcp")
+		cmd.Env = append(os.Environ(),
+			"ARGOCD_MCP_TRANSPORT=stdio",
+			"ARGOCD_MCP_DEBUG="+strconv.FormatBool(mcpServerDebug),
+			"ARGOCD_MCP_URL="+argocdURL,
+			"ARGOCD_MCP_TOKEN_FILE="+tokenFile,
+		)
+		cl := mcp.NewClient(&mcp.Implementation{Name: "e2e-test-client", Version: "v1.0.0"}, nil)
+		session, err := cl.Connect(ctx, &mcp.CommandTransport{Command: cmd}, nil)
+		require.NoError(t, err)
+		return session, func() {
+			// nothing to do
+		}
+	}
+}
+
 func newHTTPSession(mcpServerListen string, mcpServerDebug bool, argocdURL string, argocdToken string) func(*testing.T) (*mcp.ClientSession, KillMCPServerFunc) {
 	return func(t *testing.T) (*mcp.ClientSession, KillMCPServerFunc) {
 		ctx := context.Background()
@@ -9,3 +9,15 @@ var ApplicationsStr string
 
 //go:embed argocd-applications-example.json
 var ExampleApplicationStr string
+
+//go:embed argocd-application-example-resource-tree.json
+var ExampleResourceTreeStr string
+
+//go:embed argocd-application-example-managed-resources.json
+var ExampleManagedResourcesStr string
+
+//go:embed argocd-application-example-events.json
+var ExampleEventsStr string
+
+//go:embed argocd-application-example-pod-logs.json
+var ExamplePodLogsStr string